@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -18,7 +19,15 @@ import (
 const (
 	NumWorkers    = 10
 	HTTPTimeout   = 10
+	MemoryLimitMB = 0
 	DefaultScheme = "https"
+
+	BreakerMinRequests = 3
+	BreakerOpenTimeout = time.Minute
+
+	IdleConnTimeout = 30 * time.Second
+
+	StuckTimeoutFactor = 2
 )
 
 // summary keeps statistic values about the download.
@@ -29,19 +38,38 @@ type summary struct {
 	mu       sync.Mutex
 }
 
-// download implements pool.Runner interface for a pool task.
-type download struct {
-	url     string
-	timeout time.Duration
-	total   *summary
+var errStatus = errors.New("unexpected status")
+
+// page keeps the values of a downloaded index page.
+type page struct {
+	url      string
+	size     int
+	duration time.Duration
 }
 
 func main() {
 	num := flag.Int("w", NumWorkers, "Number of workers.")
 	timeout := flag.Int("t", HTTPTimeout, "HTTP timeout in seconds.")
+	memory := flag.Int("m", MemoryLimitMB, "Heap limit in MB, new downloads wait while the heap is larger (0 - no limit).")
+	trace := flag.String("trace", "", "File to write the timeline of the downloads to in Chrome trace format.")
 	flag.Parse()
 
-	total := measureDomainResponse(os.Stdin, DefaultScheme, *num, *timeout)
+	var opts []pool.Option
+	if *memory > 0 {
+		opts = append(opts, pool.WithHeapLimit(uint64(*memory)<<20, pool.AdmissionWait))
+	}
+	var rec *pool.Recorder
+	if *trace != "" {
+		rec = pool.NewRecorder(0)
+		opts = append(opts, pool.WithName("fetch"), pool.WithRecorder(rec))
+	}
+
+	total := measureDomainResponse(os.Stdin, DefaultScheme, *num, *timeout, opts...)
+	if rec != nil {
+		if err := writeTrace(*trace, rec); err != nil {
+			fmt.Printf("error: trace: %v\n", err)
+		}
+	}
 
 	fmt.Printf("\ndownloaded %.2d files, average %.2d bytes, %v\n",
 		total.num,
@@ -50,29 +78,124 @@ func main() {
 	)
 }
 
-func measureDomainResponse(input io.Reader, defaultScheme string, numWorkers int, timeoutSec int) *summary {
-	workers := pool.New(numWorkers)
-	workers.Run(context.Background())
+func measureDomainResponse(input io.Reader, defaultScheme string, numWorkers int, timeoutSec int, opts ...pool.Option) *summary {
+	// A dead host fails its remaining downloads fast instead of waiting for the timeout each time.
+	breaker := pool.NewBreaker(pool.BreakerConfig{
+		MinRequests: BreakerMinRequests,
+		OpenTimeout: BreakerOpenTimeout,
+		IsFailure: func(err error) bool {
+			return err != nil && !errors.Is(err, errStatus)
+		},
+		OnStateChange: func(host string, from, to pool.BreakerState) {
+			fmt.Printf("circuit %s: %s -> %s\n", host, from, to)
+		},
+	})
+	timeout := time.Duration(timeoutSec) * time.Second
+	total := &summary{}
+
+	// Every worker keeps its own connections instead of competing for those of http.DefaultClient.
+	opts = append(opts, pool.WithWorkerState(newClient))
+	// A download which ignores its timeout is cancelled, and its worker is replaced if that does not help.
+	opts = append(opts, pool.WithWatchdog(pool.WatchdogConfig{
+		Threshold:    StuckTimeoutFactor * timeout,
+		Cancel:       true,
+		ReplaceAfter: timeout,
+		OnStuck: func(info pool.TaskInfo, stack []byte) {
+			fmt.Printf("stuck: %s, running %s\n%s\n", info.Description, info.Elapsed, stack)
+		},
+	}))
+
+	pipeline := pool.NewPipeline(pool.PipelineConfig{
+		// A failed download is reported by the fetch stage, the others go on.
+		OnError: func(string, any, error) error { return nil },
+	},
+		pool.Stage{
+			Name: "normalise",
+			Func: func(_ context.Context, item any, emit func(any)) error {
+				emit(addScheme(item.(string), defaultScheme))
+				return nil
+			},
+		},
+		pool.Stage{
+			Name:      "fetch",
+			Workers:   numWorkers,
+			MaxQueued: numWorkers,
+			Options:   opts,
+			Func: func(ctx context.Context, item any, emit func(any)) error {
+				u := item.(string)
+				done, err := breaker.Allow(hostOf(u))
+				if err != nil {
+					return err
+				}
+
+				p, err := download(ctx, u, timeout)
+				done(err)
+				if err != nil {
+					return err
+				}
+
+				emit(p)
+				return nil
+			},
+		},
+		pool.Stage{
+			Name: "aggregate",
+			Func: func(_ context.Context, item any, _ func(any)) error {
+				p := item.(page)
+				total.Add(p.size, p.duration)
+				fmt.Printf("success: %s, size %d, duration %s\n", p.url, p.size, p.duration)
+				return nil
+			},
+		},
+	)
+	pipeline.Run(context.Background())
 	fmt.Printf("processing started with %d workers\n", numWorkers)
 
-	total := &summary{}
 	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
-		workers.Execute(download{
-			url:     addScheme(scanner.Text(), defaultScheme),
-			timeout: time.Duration(timeoutSec) * time.Second,
-			total:   total,
-		})
+		_ = pipeline.Push(context.Background(), scanner.Text())
 	}
 
 	if scanner.Err() != nil {
 		fmt.Printf("error: scanner: %v\n", scanner.Err())
 	}
-	workers.Stop()
+	_ = pipeline.Stop()
 
 	return total
 }
 
+// writeTrace writes the timeline of the downloads to a file which Perfetto or chrome://tracing opens.
+func writeTrace(name string, rec *pool.Recorder) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := rec.WriteChromeTrace(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// workerClient is an HTTP client of a worker, its idle connections are closed when the worker stops.
+type workerClient struct {
+	*http.Client
+}
+
+func newClient() workerClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 1
+	transport.IdleConnTimeout = IdleConnTimeout
+
+	return workerClient{&http.Client{Transport: transport}}
+}
+
+func (c workerClient) Close() error {
+	c.CloseIdleConnections()
+	return nil
+}
+
 func addScheme(s, scheme string) string {
 	u, err := url.Parse(s)
 	if err == nil && len(u.Scheme) == 0 {
@@ -82,41 +205,54 @@ func addScheme(s, scheme string) string {
 	return s
 }
 
-// Job does a download of an index page from a domain and measures its size and duration of the download.
-func (d download) Job(cx context.Context) {
-	ctx, cancel := context.WithTimeout(cx, d.timeout)
+func hostOf(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+
+	return u.Host
+}
+
+// download does a download of an index page from a domain and measures its size and duration of the download.
+func download(cx context.Context, u string, timeout time.Duration) (page, error) {
+	ctx, cancel := context.WithTimeout(cx, timeout)
 	defer cancel()
 
 	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
-		fmt.Printf("error: get request %s: %v\n", d.url, err)
-		return
+		fmt.Printf("error: get request %s: %v\n", u, err)
+		return page{}, err
+	}
+
+	client := http.DefaultClient
+	if c, ok := pool.WorkerState[workerClient](cx); ok {
+		client = c.Client
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	duration := time.Since(start)
 	if err != nil {
-		fmt.Printf("error: getting %s: %v\n", d.url, err)
-		return
+		fmt.Printf("error: getting %s: %v\n", u, err)
+		return page{}, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("error: getting %s: status %d %s\n", d.url, resp.StatusCode, http.StatusText(resp.StatusCode))
-		return
+		fmt.Printf("error: getting %s: status %d %s\n", u, resp.StatusCode, http.StatusText(resp.StatusCode))
+		return page{}, fmt.Errorf("%w %d", errStatus, resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Printf("error: reading %s: %v\n", d.url, err)
-		return
+		fmt.Printf("error: reading %s: %v\n", u, err)
+		return page{}, err
 	}
 
-	d.total.Add(len(body), duration)
-	fmt.Printf("success: %s, size %d, duration %s\n", d.url, len(body), duration)
+	return page{url: u, size: len(body), duration: duration}, nil
 }
 
 // Add increments download statistics thread safely.
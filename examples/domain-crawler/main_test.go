@@ -30,4 +30,23 @@ func Test_measureDomainResponse(t *testing.T) {
 		got := measureDomainResponse(strings.NewReader(inp), "https", 10, 1)
 		assert.Equal(t, got.num, 10)
 	})
+
+	t.Run("dead host", func(t *testing.T) {
+		dead := httptest.NewServer(http.NotFoundHandler())
+		dead.Close()
+
+		live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{}"))
+		}))
+		defer live.Close()
+
+		inp := ""
+		for i := 0; i < 10; i++ {
+			inp = fmt.Sprintf("%s%s/%d\n", inp, dead.URL, i)
+		}
+		inp = fmt.Sprintf("%s%s\n%s/1\n", inp, live.URL, live.URL)
+
+		got := measureDomainResponse(strings.NewReader(inp), "https", 2, 1)
+		assert.Equal(t, got.num, 2)
+	})
 }
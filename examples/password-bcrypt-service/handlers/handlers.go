@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -15,6 +17,11 @@ import (
 	"github.com/illyasch/worker-pool/pool"
 )
 
+const (
+	// MaxBatchPasswords bounds the number of passwords in a request to /bcrypt/batch.
+	MaxBatchPasswords = 100
+)
+
 var (
 	ErrScheduleTimeout = fmt.Errorf("task scheduling timeout")
 )
@@ -24,6 +31,7 @@ type APIConfig struct {
 	BusyTimeout    time.Duration
 	Log            *log.Logger
 	Workers        *pool.NonBlocking[string]
+	Batcher        *pool.Batcher[string, string]
 	PasswordMinLen int
 }
 
@@ -32,6 +40,24 @@ type response struct {
 	Hash  string `json:"hash"`
 }
 
+// healthResponse reports the state of the pool of workers.
+type healthResponse struct {
+	Status string `json:"status"`
+	Queued int    `json:"queued"`
+	Wait   string `json:"wait"`
+	Busy   string `json:"busy"`
+}
+
+type batchResponse struct {
+	Error  string   `json:"error,omitempty"`
+	Hashes []string `json:"hashes"`
+}
+
+// BcryptBatch implements pool.BatchRunner interface, it hashes a batch of passwords in one worker.
+type BcryptBatch struct {
+	Log *log.Logger
+}
+
 type bcryptTask struct {
 	log      *log.Logger
 	password string
@@ -41,6 +67,11 @@ type bcryptTask struct {
 func (cfg APIConfig) Router() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/bcrypt", cfg.handleBcrypt)
+	mux.HandleFunc("/healthz", cfg.handleHealthz)
+	mux.HandleFunc("/readyz", cfg.handleReadyz)
+	if cfg.Batcher != nil {
+		mux.HandleFunc("/bcrypt/batch", cfg.handleBcryptBatch)
+	}
 
 	return mux
 }
@@ -61,16 +92,23 @@ func (cfg APIConfig) handleBcrypt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, err := cfg.scheduleBcrypt(pwd)
+	hash, err := cfg.scheduleBcrypt(r.Context(), pwd)
 	if err == nil {
 		cfg.respond(w, http.StatusOK, response{Hash: hash})
 		cfg.Log.Println("bcrypt", "statusCode", http.StatusOK, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 		return
 	}
 
-	if errors.Is(err, ErrScheduleTimeout) {
+	var overload *pool.OverloadError
+	switch {
+	case errors.As(err, &overload):
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(overload.RetryAfter)))
+		cfg.respond(w, http.StatusServiceUnavailable, response{Error: http.StatusText(http.StatusServiceUnavailable)})
+	case unavailable(err):
+		cfg.respond(w, http.StatusServiceUnavailable, response{Error: http.StatusText(http.StatusServiceUnavailable)})
+	case errors.Is(err, ErrScheduleTimeout):
 		cfg.respond(w, http.StatusTooManyRequests, response{Error: http.StatusText(http.StatusTooManyRequests)})
-	} else {
+	default:
 		cfg.respond(w, http.StatusInternalServerError, response{Error: http.StatusText(http.StatusInternalServerError)})
 	}
 
@@ -78,42 +116,131 @@ func (cfg APIConfig) handleBcrypt(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func (cfg APIConfig) handleBcryptBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		cfg.respond(w, http.StatusMethodNotAllowed, batchResponse{Error: http.StatusText(http.StatusMethodNotAllowed)})
+		cfg.Log.Println("bcrypt batch", "ERROR", fmt.Errorf("incorrect request method %s", r.Method))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		cfg.respond(w, http.StatusBadRequest, batchResponse{Error: http.StatusText(http.StatusBadRequest)})
+		cfg.Log.Println("bcrypt batch", "ERROR", fmt.Errorf("parse form: %w", err))
+		return
+	}
+
+	pwds := r.PostForm["password"]
+	if len(pwds) == 0 || len(pwds) > MaxBatchPasswords {
+		err := fmt.Errorf("number of passwords is not between 1 and %d", MaxBatchPasswords)
+
+		cfg.respond(w, http.StatusBadRequest, batchResponse{Error: err.Error()})
+		cfg.Log.Println("bcrypt batch", "ERROR", fmt.Errorf("validation: %w", err))
+		return
+	}
+	for _, pwd := range pwds {
+		if len(pwd) < cfg.PasswordMinLen {
+			err := errors.New("input password is incorrect")
+
+			cfg.respond(w, http.StatusBadRequest, batchResponse{Error: err.Error()})
+			cfg.Log.Println("bcrypt batch", "ERROR", fmt.Errorf("validation password(%s): %w", pwd, err))
+			return
+		}
+	}
+
+	// The passwords are coalesced with those of the other requests into batches.
+	hashes := make([]string, len(pwds))
+	errs := make([]error, len(pwds))
+	var wg sync.WaitGroup
+	for i, pwd := range pwds {
+		wg.Add(1)
+		go func(i int, pwd string) {
+			defer wg.Done()
+
+			resp := cfg.Batcher.Submit(r.Context(), pwd)
+			hashes[i], errs[i] = resp.Value, resp.Err
+		}(i, pwd)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			statusCode := http.StatusInternalServerError
+			if unavailable(err) {
+				statusCode = http.StatusServiceUnavailable
+			}
+
+			cfg.respond(w, statusCode, batchResponse{Error: http.StatusText(statusCode)})
+			cfg.Log.Println("bcrypt batch", "ERROR", fmt.Errorf("bcrypt: %w", err))
+			return
+		}
+	}
+
+	cfg.respond(w, http.StatusOK, batchResponse{Hashes: hashes})
+	cfg.Log.Println("bcrypt batch", "statusCode", http.StatusOK, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+// handleHealthz responds with 200 while the pool of workers is running, even if it is overloaded.
+func (cfg APIConfig) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	h := cfg.Workers.Health()
+	cfg.respondHealth(w, h, h.Live())
+}
+
+// handleReadyz responds with 503 when the pool of workers does not take new requests without delay,
+// e.g. every worker has been busy longer than the threshold of pool.WithHealth, so a load balancer
+// stops routing requests to the service.
+func (cfg APIConfig) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	h := cfg.Workers.Health()
+	cfg.respondHealth(w, h, h.Ready())
+}
+
+func (cfg APIConfig) respondHealth(w http.ResponseWriter, h pool.Health, ok bool) {
+	statusCode := http.StatusOK
+	if !ok {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	cfg.respond(w, statusCode, healthResponse{
+		Status: h.Status.String(),
+		Queued: h.Queued,
+		Wait:   h.Wait.String(),
+		Busy:   h.Busy.String(),
+	})
+}
+
 // scheduleBcrypt sends a request for execution of a bcrypt task to a free worker.
 // If there is no available worker or a task execution takes longer than cfg.BusyTimeout,
-// it returns ErrScheduleTimeout.
-func (cfg APIConfig) scheduleBcrypt(pwd string) (string, error) {
+// it returns ErrScheduleTimeout. If the pool sheds the request, it returns pool.OverloadError.
+func (cfg APIConfig) scheduleBcrypt(ctx context.Context, pwd string) (string, error) {
 	task := bcryptTask{
 		log:      cfg.Log,
 		password: pwd,
 	}
 
-	// Gets a channel to get a free worker.
-	requests := cfg.Workers.RequestChan()
-	timer := time.NewTimer(cfg.BusyTimeout)
-	defer timer.Stop()
-
-	select {
-	// Retrieves a first free worker for a task execution.
-	case req := <-requests:
-		// Closes the channels bound to the worker to avoid blocking.
-		defer req.Close()
-		select {
-		// Sends a task to the worker then worker starts the task execution.
-		case req.Request <- task:
-			select {
-			// Waits until the task execution is finished and retrieves a response struct.
-			case resp := <-req.Response:
-				return resp.Value, resp.Err
-			case <-timer.C:
-			}
+	ctx, cancel := context.WithTimeout(ctx, cfg.BusyTimeout)
+	defer cancel()
 
-		case <-timer.C:
-		}
+	// Waits for a free worker, executes the task in it and retrieves a response struct.
+	resp := cfg.Workers.Submit(ctx, task)
+	if resp.Err != nil && ctx.Err() != nil {
+		return "", ErrScheduleTimeout
+	}
+
+	return resp.Value, resp.Err
+}
 
-	case <-timer.C:
+// unavailable reports whether the pool does not take tasks because it is draining or stopped.
+func unavailable(err error) bool {
+	return errors.Is(err, pool.ErrPoolDraining) || errors.Is(err, pool.ErrPoolStopped)
+}
+
+// retryAfterSeconds converts a delay to the value of the Retry-After header.
+func retryAfterSeconds(d time.Duration) int {
+	sec := int((d + time.Second - 1) / time.Second)
+	if sec < 1 {
+		return 1
 	}
 
-	return "", ErrScheduleTimeout
+	return sec
 }
 
 func (r bcryptTask) Job(context.Context) pool.JobResponse[string] {
@@ -127,6 +254,16 @@ func (r bcryptTask) Job(context.Context) pool.JobResponse[string] {
 	return pool.JobResponse[string]{Value: string(hash)}
 }
 
+// Job hashes every password of the batch.
+func (b BcryptBatch) Job(ctx context.Context, pwds []string) []pool.JobResponse[string] {
+	resp := make([]pool.JobResponse[string], len(pwds))
+	for i, pwd := range pwds {
+		resp[i] = bcryptTask{log: b.Log, password: pwd}.Job(ctx)
+	}
+
+	return resp
+}
+
 func (cfg APIConfig) respond(w http.ResponseWriter, statusCode int, data any) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
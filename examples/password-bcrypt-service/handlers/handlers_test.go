@@ -11,6 +11,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -128,6 +129,88 @@ func TestMakeHandler(t *testing.T) {
 		require.Equal(t, "input password is incorrect", resp.Error)
 	})
 
+	t.Run(`overloaded queue`, func(t *testing.T) {
+		t.Parallel()
+		workers := pool.NewNonBlocking[string](1, pool.WithQueue(pool.QueueConfig{
+			Target: time.Second,
+			MaxLen: 1,
+		}))
+		workers.Run(context.Background())
+		cfg := handlers.APIConfig{
+			BusyTimeout:    time.Second,
+			Log:            stdLgr,
+			Workers:        workers,
+			PasswordMinLen: 8,
+		}
+
+		// Occupies the only worker and the only place in the queue.
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+		defer busy.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			if req, err := workers.Acquire(ctx); err == nil {
+				req.Close()
+			}
+		}()
+		require.Eventually(t, func() bool { return workers.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		vals := url.Values{}
+		vals.Set("password", "qwertyegegrggeeggre")
+		req := httptest.NewRequest(http.MethodPost, "/bcrypt", strings.NewReader(vals.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		cfg.Router().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run(`bcrypt a batch of passwords`, func(t *testing.T) {
+		t.Parallel()
+		pwds := []string{"qwertyuuiiopasdfg1233456969", "asdfghjklzxcvbnm", "zxcvbnmqwertyuiop"}
+
+		workers := pool.NewNonBlocking[string](1)
+		workers.Run(context.Background())
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxSize: 3, MaxLinger: time.Second}, handlers.BcryptBatch{Log: stdLgr})
+		batcher.Run(context.Background())
+		defer batcher.Stop()
+		cfg := handlers.APIConfig{
+			BusyTimeout:    time.Second,
+			Log:            stdLgr,
+			Workers:        workers,
+			Batcher:        batcher,
+			PasswordMinLen: 8,
+		}
+
+		vals := url.Values{"password": pwds}
+		req := httptest.NewRequest(http.MethodPost, "/bcrypt/batch", strings.NewReader(vals.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		cfg.Router().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		resp := struct {
+			Error  string   `json:"error"`
+			Hashes []string `json:"hashes"`
+		}{}
+		err := json.NewDecoder(w.Body).Decode(&resp)
+		require.NoError(t, err)
+		require.Empty(t, resp.Error)
+		require.Len(t, resp.Hashes, len(pwds))
+
+		for i, pwd := range pwds {
+			err = bcrypt.CompareHashAndPassword([]byte(resp.Hashes[i]), []byte(pwd))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, uint64(1), batcher.Stats().Completed)
+	})
+
 	t.Run(`busy timeout`, func(t *testing.T) {
 		t.Parallel()
 		workers := pool.NewNonBlocking[string](runtime.NumCPU())
@@ -154,4 +237,138 @@ func TestMakeHandler(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "Too Many Requests", resp.Error)
 	})
+
+	t.Run(`draining pool`, func(t *testing.T) {
+		t.Parallel()
+		workers := pool.NewNonBlocking[string](1)
+		workers.Run(context.Background())
+		defer workers.Stop()
+		require.NoError(t, workers.Drain(context.Background()))
+		cfg := handlers.APIConfig{
+			BusyTimeout:    time.Second,
+			Log:            stdLgr,
+			Workers:        workers,
+			PasswordMinLen: 8,
+		}
+
+		vals := url.Values{}
+		vals.Set("password", "qwertyegegrggeeggre")
+		req := httptest.NewRequest(http.MethodPost, "/bcrypt", strings.NewReader(vals.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+		cfg.Router().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+// hold occupies a worker until it is released.
+type hold chan struct{}
+
+func (h hold) Job(context.Context) pool.JobResponse[string] {
+	<-h
+	return pool.JobResponse[string]{}
+}
+
+// sleep occupies a worker for a while.
+type sleep time.Duration
+
+func (s sleep) Job(context.Context) pool.JobResponse[string] {
+	time.Sleep(time.Duration(s))
+	return pool.JobResponse[string]{}
+}
+
+func TestHealth_ContinuousLoad(t *testing.T) {
+	const busyTimeout = 30 * time.Millisecond
+	workers := pool.NewNonBlocking[string](2, pool.WithHealth(pool.HealthConfig{MaxBusy: busyTimeout, MaxWait: busyTimeout}))
+	workers.Run(context.Background())
+	defer workers.Stop()
+	router := handlers.APIConfig{BusyTimeout: busyTimeout, Log: stdLgr, Workers: workers}.Router()
+
+	readyz := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, readyz())
+
+	// The requests keep every worker busy with short tasks, many of them time out.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), busyTimeout)
+				workers.Submit(ctx, sleep(5*time.Millisecond))
+				cancel()
+			}
+		}()
+	}
+
+	require.Eventually(t, func() bool { return readyz() == http.StatusServiceUnavailable }, time.Second, time.Millisecond)
+
+	close(stop)
+	wg.Wait()
+	require.Eventually(t, func() bool { return readyz() == http.StatusOK }, time.Second, time.Millisecond)
+}
+
+func TestHealth(t *testing.T) {
+	workers := pool.NewNonBlocking[string](1, pool.WithHealth(pool.HealthConfig{MaxBusy: 20 * time.Millisecond}))
+	cfg := handlers.APIConfig{
+		BusyTimeout: 20 * time.Millisecond,
+		Log:         stdLgr,
+		Workers:     workers,
+	}
+
+	get := func(path string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		cfg.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var resp map[string]any
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w.Code, resp
+	}
+
+	code, resp := get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "stopped", resp["status"])
+
+	workers.Run(context.Background())
+	code, _ = get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	code, resp = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "running", resp["status"])
+
+	// The only worker is busy longer than BusyTimeout.
+	release := make(hold)
+	h := workers.Start(context.Background(), release)
+	require.Eventually(t, func() bool {
+		code, _ := get("/readyz")
+		return code == http.StatusServiceUnavailable
+	}, time.Second, time.Millisecond)
+	_, resp = get("/readyz")
+	assert.Equal(t, "overloaded", resp["status"])
+	code, _ = get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	close(release)
+	require.NoError(t, h.Wait(context.Background()))
+	require.Eventually(t, func() bool {
+		code, _ := get("/readyz")
+		return code == http.StatusOK
+	}, time.Second, time.Millisecond)
+
+	workers.Stop()
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
 }
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/illyasch/worker-pool/examples/password-bcrypt-service/handlers"
 	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/admin"
 )
 
 const (
@@ -27,6 +30,18 @@ type config struct {
 	NumWorkers      int           `conf:"default:10"`
 	ShutdownTimeout time.Duration `conf:"default:20s"`
 	BusyTimeout     time.Duration `conf:"default:100ms"`
+	AdaptiveLimit   bool          `conf:"default:false"`
+	QueueTarget     time.Duration `conf:"default:0s"`
+	QueueInterval   time.Duration `conf:"default:100ms"`
+	QueueMaxLen     int           `conf:"default:0"`
+	QueueLIFO       bool          `conf:"default:false"`
+	WorkerCPUs      []int         `conf:"env:WORKER_CPUS,flag:worker-cpus"`
+	WorkerNice      int           `conf:"default:0"`
+	BatchWorkers    int           `conf:"default:0"`
+	BatchSize       int           `conf:"default:10"`
+	BatchLinger     time.Duration `conf:"default:10ms"`
+	AdminHost       string
+	AdminToken      string `conf:"mask"`
 }
 
 func main() {
@@ -45,6 +60,10 @@ func run(logger *log.Logger) error {
 		}
 		return fmt.Errorf("parsing config: %w", err)
 	}
+	// The admin endpoints control the pool, they are never served without authorisation.
+	if cfg.AdminHost != "" && cfg.AdminToken == "" {
+		return errors.New("admin host is set without an admin token")
+	}
 
 	// =========================================================================
 	// App Starting
@@ -53,10 +72,56 @@ func run(logger *log.Logger) error {
 	defer logger.Println("shutdown complete")
 
 	// Start worker pool.
-	workers := pool.NewNonBlocking[string](cfg.NumWorkers)
+	// The named pools publish their statistic values with expvar and label their tasks in the CPU profiles.
+	// /readyz reports the service not ready while every worker has been busy longer than BusyTimeout
+	// or a request has been waiting for a worker longer than that.
+	opts := []pool.Option{
+		pool.WithName("bcrypt"),
+		pool.WithHealth(pool.HealthConfig{MaxBusy: cfg.BusyTimeout, MaxWait: cfg.BusyTimeout}),
+	}
+	if cfg.AdaptiveLimit {
+		// NumWorkers becomes the upper bound, the limit follows the bcrypt latency.
+		opts = append(opts, pool.WithLimiter(pool.NewAIMDLimiter(pool.AIMDConfig{
+			Initial:          cfg.NumWorkers,
+			Min:              1,
+			Max:              cfg.NumWorkers,
+			LatencyThreshold: cfg.BusyTimeout,
+		})))
+	}
+	if cfg.QueueTarget > 0 {
+		// Requests waiting for a worker longer than QueueTarget are shed with 503 and Retry-After.
+		opts = append(opts, pool.WithQueue(pool.QueueConfig{
+			Target:   cfg.QueueTarget,
+			Interval: cfg.QueueInterval,
+			MaxLen:   cfg.QueueMaxLen,
+			LIFO:     cfg.QueueLIFO,
+		}))
+	}
+	if len(cfg.WorkerCPUs) > 0 || cfg.WorkerNice != 0 {
+		// Keeps the hashing off the CPUs of the latency-critical goroutines.
+		opts = append(opts, pool.WithThreads(pool.ThreadConfig{
+			CPUs: cfg.WorkerCPUs,
+			Nice: cfg.WorkerNice,
+			OnError: func(err error) {
+				logger.Println("startup", "ERROR", fmt.Errorf("worker thread: %w", err))
+			},
+		}))
+	}
+	workers := pool.NewNonBlocking[string](cfg.NumWorkers, opts...)
 	workers.Run(context.Background())
 	defer workers.Stop()
 
+	// Start the batcher behind /bcrypt/batch if it has workers.
+	var batcher *pool.Batcher[string, string]
+	if cfg.BatchWorkers > 0 {
+		batcher = pool.NewBatcher[string, string](cfg.BatchWorkers, pool.BatchConfig{
+			MaxSize:   cfg.BatchSize,
+			MaxLinger: cfg.BatchLinger,
+		}, handlers.BcryptBatch{Log: logger}, pool.WithName("bcrypt-batch"))
+		batcher.Run(context.Background())
+		defer batcher.Stop()
+	}
+
 	// =========================================================================
 	// Start API Service
 
@@ -72,6 +137,7 @@ func run(logger *log.Logger) error {
 		BusyTimeout: cfg.BusyTimeout,
 		Log:         logger,
 		Workers:     workers,
+		Batcher:     batcher,
 	}.Router()
 
 	// Construct a server to service the requests against the mux.
@@ -81,9 +147,9 @@ func run(logger *log.Logger) error {
 		ErrorLog: logger,
 	}
 
-	// Make a channel to listen for errors coming from the listener. Use a
-	// buffered channel so the goroutine can exit if we don't collect this error.
-	serverErrors := make(chan error, 1)
+	// Make a channel to listen for errors coming from the API and admin listeners. Use a
+	// buffered channel so the goroutines can exit if we don't collect these errors.
+	serverErrors := make(chan error, 2)
 
 	// Start the service listening for srv requests.
 	go func() {
@@ -91,6 +157,27 @@ func run(logger *log.Logger) error {
 		serverErrors <- srv.ListenAndServe()
 	}()
 
+	// Start the admin listener if it is configured, operators tune the pool through it.
+	if cfg.AdminHost != "" {
+		auth := adminAuth(cfg.AdminToken)
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/", admin.NewHandler(workers, admin.Config{Auth: auth}))
+		// The default mux serves the expvar values and the pprof profiles under /debug/.
+		adminMux.Handle("/debug/", authorised(auth, http.DefaultServeMux))
+
+		adminSrv := http.Server{
+			Addr:     cfg.AdminHost,
+			Handler:  adminMux,
+			ErrorLog: logger,
+		}
+		defer adminSrv.Close()
+
+		go func() {
+			logger.Println("startup", "status", "admin router started", "host", adminSrv.Addr)
+			serverErrors <- adminSrv.ListenAndServe()
+		}()
+	}
+
 	// =========================================================================
 	// Shutdown
 
@@ -119,6 +206,29 @@ func run(logger *log.Logger) error {
 	return nil
 }
 
+// adminAuth returns the authorisation of the admin requests by a bearer token.
+func adminAuth(token string) func(r *http.Request) error {
+	want := []byte("Bearer " + token)
+	return func(r *http.Request) error {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			return errors.New("invalid admin token")
+		}
+		return nil
+	}
+}
+
+// authorised lets only the requests accepted by auth in.
+func authorised(auth func(r *http.Request) error, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := auth(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
 func parseConfig(prefix string, logger *log.Logger) (config, error) {
 	cfg := config{
 		Version: conf.Version{
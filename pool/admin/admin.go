@@ -0,0 +1,261 @@
+// Package admin serves the introspection and control of a worker pool over HTTP.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+var (
+	ErrTaskNotFound = errors.New("task not found")
+	ErrNoAuth       = errors.New("admin requests are not authorised")
+)
+
+// Pool is a worker pool served by the handler. Both pool.Pool and pool.NonBlocking[T] implement it.
+type Pool interface {
+	Stats() pool.Stats
+	Snapshot() pool.Snapshot
+	Task(id uint64) (*pool.Handle, bool)
+	Resize(n int) error
+	Pause()
+	Resume()
+	Paused() bool
+	Drain(ctx context.Context) error
+	Draining() bool
+}
+
+// Config keeps the settings of the handler.
+type Config struct {
+	// Auth authorises a request, the handler responds 403 Forbidden if it returns an error.
+	// Nil denies all requests, use AllowAll to serve a trusted network without authorisation.
+	Auth func(r *http.Request) error
+	// DrainTimeout bounds the wait of a request to /drain. Default is 30s.
+	DrainTimeout time.Duration
+}
+
+type handler struct {
+	cfg  Config
+	pool Pool
+	mux  *http.ServeMux
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type statsResponse struct {
+	Workers   int    `json:"workers"`
+	Live      int    `json:"live"`
+	Idle      int    `json:"idle"`
+	Running   int    `json:"running"`
+	Queued    int    `json:"queued"`
+	Limit     int    `json:"limit"`
+	Latency   string `json:"latency"`
+	Completed uint64 `json:"completed"`
+	Failed    uint64 `json:"failed"`
+	Rejected  uint64 `json:"rejected"`
+	Paused    bool   `json:"paused"`
+	Draining  bool   `json:"draining"`
+}
+
+type taskResponse struct {
+	ID          uint64     `json:"id"`
+	Status      string     `json:"status"`
+	Worker      int        `json:"worker,omitempty"`
+	Queued      time.Time  `json:"queued"`
+	Started     *time.Time `json:"started,omitempty"`
+	Elapsed     string     `json:"elapsed"`
+	Description string     `json:"description,omitempty"`
+}
+
+type snapshotResponse struct {
+	Taken   time.Time      `json:"taken"`
+	Running []taskResponse `json:"running"`
+	Queued  []taskResponse `json:"queued"`
+}
+
+// NewHandler creates a handler serving the pool on the paths:
+//
+//	GET  /stats                 - the statistic values of the pool and whether it is paused or draining
+//	GET  /tasks                 - the running and queued tasks
+//	POST /tasks/cancel?id=ID    - cancels a task
+//	POST /resize?workers=N      - changes the number of workers
+//	POST /pause                 - stops the workers from taking new tasks
+//	POST /resume                - resumes a paused or drained pool
+//	POST /drain                 - stops accepting tasks and waits until the pool has none
+//
+// Mount it under a prefix with http.StripPrefix.
+func NewHandler(p Pool, cfg Config) http.Handler {
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 30 * time.Second
+	}
+
+	h := &handler{cfg: cfg, pool: p, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/stats", h.method(http.MethodGet, h.handleStats))
+	h.mux.HandleFunc("/tasks", h.method(http.MethodGet, h.handleTasks))
+	h.mux.HandleFunc("/tasks/cancel", h.method(http.MethodPost, h.handleCancel))
+	h.mux.HandleFunc("/resize", h.method(http.MethodPost, h.handleResize))
+	h.mux.HandleFunc("/pause", h.method(http.MethodPost, h.handlePause))
+	h.mux.HandleFunc("/resume", h.method(http.MethodPost, h.handleResume))
+	h.mux.HandleFunc("/drain", h.method(http.MethodPost, h.handleDrain))
+
+	return h
+}
+
+// AllowAll is Config.Auth letting all requests in.
+func AllowAll(*http.Request) error {
+	return nil
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := ErrNoAuth
+	if h.cfg.Auth != nil {
+		err = h.cfg.Auth(r)
+	}
+	if err != nil {
+		respond(w, http.StatusForbidden, errorResponse{Error: err.Error()})
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// method allows only the requests with the method to the handler function.
+func (h *handler) method(method string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			respond(w, http.StatusMethodNotAllowed, errorResponse{Error: http.StatusText(http.StatusMethodNotAllowed)})
+			return
+		}
+
+		fn(w, r)
+	}
+}
+
+func (h *handler) handleStats(w http.ResponseWriter, _ *http.Request) {
+	h.respondStats(w)
+}
+
+func (h *handler) handleTasks(w http.ResponseWriter, _ *http.Request) {
+	s := h.pool.Snapshot()
+	resp := snapshotResponse{
+		Taken:   s.Taken,
+		Running: make([]taskResponse, 0, len(s.Running)),
+		Queued:  make([]taskResponse, 0, len(s.Queued)),
+	}
+	for _, t := range s.Running {
+		resp.Running = append(resp.Running, newTaskResponse(t))
+	}
+	for _, t := range s.Queued {
+		resp.Queued = append(resp.Queued, newTaskResponse(t))
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+func (h *handler) handleCancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respond(w, http.StatusBadRequest, errorResponse{Error: "incorrect task id"})
+		return
+	}
+
+	t, ok := h.pool.Task(id)
+	if !ok {
+		respond(w, http.StatusNotFound, errorResponse{Error: ErrTaskNotFound.Error()})
+		return
+	}
+	t.Cancel()
+
+	respond(w, http.StatusOK, newTaskResponse(t.Info()))
+}
+
+func (h *handler) handleResize(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.FormValue("workers"))
+	if err != nil {
+		respond(w, http.StatusBadRequest, errorResponse{Error: "incorrect number of workers"})
+		return
+	}
+
+	if err := h.pool.Resize(n); err != nil {
+		respond(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	h.respondStats(w)
+}
+
+func (h *handler) handlePause(w http.ResponseWriter, _ *http.Request) {
+	h.pool.Pause()
+	h.respondStats(w)
+}
+
+func (h *handler) handleResume(w http.ResponseWriter, _ *http.Request) {
+	h.pool.Resume()
+	h.respondStats(w)
+}
+
+func (h *handler) handleDrain(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.cfg.DrainTimeout)
+	defer cancel()
+
+	if err := h.pool.Drain(ctx); err != nil {
+		respond(w, http.StatusGatewayTimeout, errorResponse{Error: err.Error()})
+		return
+	}
+
+	h.respondStats(w)
+}
+
+func (h *handler) respondStats(w http.ResponseWriter) {
+	s := h.pool.Stats()
+	respond(w, http.StatusOK, statsResponse{
+		Workers:   s.Workers,
+		Live:      s.Live,
+		Idle:      s.Idle,
+		Running:   s.Running,
+		Queued:    s.Queued,
+		Limit:     s.Limit,
+		Latency:   s.Latency.String(),
+		Completed: s.Completed,
+		Failed:    s.Failed,
+		Rejected:  s.Rejected,
+		Paused:    h.pool.Paused(),
+		Draining:  h.pool.Draining(),
+	})
+}
+
+func newTaskResponse(t pool.TaskInfo) taskResponse {
+	resp := taskResponse{
+		ID:          t.ID,
+		Status:      t.Status.String(),
+		Worker:      t.Worker,
+		Queued:      t.Queued,
+		Elapsed:     t.Elapsed.String(),
+		Description: t.Description,
+	}
+	if !t.Started.IsZero() {
+		resp.Started = &t.Started
+	}
+
+	return resp
+}
+
+func respond(w http.ResponseWriter, statusCode int, data any) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(jsonData)
+}
@@ -0,0 +1,187 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/admin"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+type stats struct {
+	Workers  int  `json:"workers"`
+	Live     int  `json:"live"`
+	Paused   bool `json:"paused"`
+	Draining bool `json:"draining"`
+}
+
+type task struct {
+	ID          uint64 `json:"id"`
+	Status      string `json:"status"`
+	Worker      int    `json:"worker"`
+	Elapsed     string `json:"elapsed"`
+	Description string `json:"description"`
+}
+
+type snapshot struct {
+	Running []task `json:"running"`
+	Queued  []task `json:"queued"`
+}
+
+// waiter runs until its context is done.
+type waiter chan struct{}
+
+func (w waiter) Job(ctx context.Context) {
+	close(w)
+	<-ctx.Done()
+}
+
+func (w waiter) Describe() string {
+	return "waiter"
+}
+
+func serve(t *testing.T, h http.Handler, method, target string, out any) int {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	if out != nil {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(out))
+	}
+
+	return w.Code
+}
+
+func TestNewHandler(t *testing.T) {
+	workers := pool.New(2, pool.WithTenants(pool.TenantConfig{}))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	h := admin.NewHandler(workers, admin.Config{Auth: admin.AllowAll})
+
+	t.Run("Stats", func(t *testing.T) {
+		var s stats
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/stats", &s))
+		assert.Equal(t, 2, s.Workers)
+		assert.Equal(t, 2, s.Live)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(t, h, http.MethodPost, "/stats", nil))
+	})
+
+	t.Run("Tasks and cancel", func(t *testing.T) {
+		started := make(waiter)
+		handle := workers.Execute(started)
+		<-started
+
+		var s snapshot
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/tasks", &s))
+		require.Len(t, s.Running, 1)
+		assert.Equal(t, handle.ID(), s.Running[0].ID)
+		assert.Equal(t, "running", s.Running[0].Status)
+		assert.Equal(t, "waiter", s.Running[0].Description)
+		assert.Empty(t, s.Queued)
+
+		target := "/tasks/cancel?id=" + strconv.FormatUint(handle.ID(), 10)
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, target, nil))
+		require.NoError(t, handle.Wait(context.Background()))
+		assert.Equal(t, pool.TaskCancelled, handle.Status())
+
+		assert.Equal(t, http.StatusNotFound, serve(t, h, http.MethodPost, target, nil))
+		assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/tasks/cancel?id=x", nil))
+	})
+
+	t.Run("Resize", func(t *testing.T) {
+		var s stats
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/resize?workers=4", &s))
+		assert.Equal(t, 4, s.Workers)
+		assert.Equal(t, 4, s.Live)
+
+		assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/resize?workers=0", nil))
+		assert.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/resize", nil))
+	})
+
+	t.Run("Pause, drain and resume", func(t *testing.T) {
+		var s stats
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/pause", &s))
+		assert.True(t, s.Paused)
+
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/drain", &s))
+		assert.False(t, s.Paused)
+		assert.True(t, s.Draining)
+
+		require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/resume", &s))
+		assert.False(t, s.Draining)
+	})
+
+	t.Run("Drain timeout", func(t *testing.T) {
+		h := admin.NewHandler(workers, admin.Config{Auth: admin.AllowAll, DrainTimeout: 10 * time.Millisecond})
+
+		started := make(waiter)
+		handle := workers.Execute(started)
+		<-started
+		defer handle.Cancel()
+		defer workers.Resume()
+
+		assert.Equal(t, http.StatusGatewayTimeout, serve(t, h, http.MethodPost, "/drain", nil))
+	})
+}
+
+func TestNewHandler_Cancel(t *testing.T) {
+	clock := pooltest.NewClock(time.Now())
+	workers := pool.New(1, pool.WithClock(clock))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	h := admin.NewHandler(workers, admin.Config{Auth: admin.AllowAll})
+
+	started := make(waiter)
+	handle := workers.Execute(started)
+	<-started
+	clock.Advance(2 * time.Second)
+
+	var cancelled task
+	target := "/tasks/cancel?id=" + strconv.FormatUint(handle.ID(), 10)
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, target, &cancelled))
+	assert.Equal(t, handle.ID(), cancelled.ID)
+	assert.Equal(t, "2s", cancelled.Elapsed)
+}
+
+func TestNewHandler_Auth(t *testing.T) {
+	workers := pool.NewNonBlocking[string](1)
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	h := admin.NewHandler(workers, admin.Config{
+		Auth: func(r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				return errors.New("unauthorised")
+			}
+			return nil
+		},
+	})
+
+	assert.Equal(t, http.StatusForbidden, serve(t, h, http.MethodGet, "/stats", nil))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	t.Run("No Auth denies all", func(t *testing.T) {
+		h := admin.NewHandler(workers, admin.Config{})
+		assert.Equal(t, http.StatusForbidden, serve(t, h, http.MethodGet, "/stats", nil))
+		assert.Equal(t, http.StatusForbidden, serve(t, h, http.MethodPost, "/pause", nil))
+		assert.False(t, workers.Paused())
+	})
+}
@@ -0,0 +1,33 @@
+package pool
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var (
+	ErrAffinityUnsupported = fmt.Errorf("thread affinity is not supported on %s", runtime.GOOS)
+)
+
+// ThreadConfig keeps the settings of the OS threads of the workers.
+type ThreadConfig struct {
+	// CPUs is the set of CPUs the workers run on, empty means any CPU.
+	CPUs []int
+	// Nice is the nice level of the worker threads, 0 keeps the level of the process.
+	Nice int
+	// OnError is called by a worker whose thread could not be configured, the worker runs anyway.
+	OnError func(err error)
+}
+
+// pin locks the starting worker to its OS thread and configures the thread.
+// The thread is never unlocked, so it exits with the worker instead of returning to the Go scheduler.
+func (c *core) pin() {
+	if c.cfg.thread == nil {
+		return
+	}
+
+	runtime.LockOSThread()
+	if err := setThread(*c.cfg.thread); err != nil && c.cfg.thread.OnError != nil {
+		c.cfg.thread.OnError(err)
+	}
+}
@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// cpuSetWords is the size of the CPU set in the words of the mask, enough for 1024 CPUs.
+const cpuSetWords = 16
+
+// setThread applies the CPU set and the nice level to the current thread.
+func setThread(cfg ThreadConfig) error {
+	if len(cfg.CPUs) > 0 {
+		var mask [cpuSetWords]uint64
+		for _, cpu := range cfg.CPUs {
+			if cpu < 0 || cpu >= cpuSetWords*64 {
+				return fmt.Errorf("sched_setaffinity: invalid CPU %d", cpu)
+			}
+			mask[cpu/64] |= 1 << (cpu % 64)
+		}
+
+		// The thread ID 0 means the calling thread.
+		_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+		if errno != 0 {
+			return fmt.Errorf("sched_setaffinity: %w", errno)
+		}
+	}
+
+	if cfg.Nice != 0 {
+		// On Linux, the nice level belongs to a thread, not to the whole process.
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), cfg.Nice); err != nil {
+			return fmt.Errorf("setpriority: %w", err)
+		}
+	}
+
+	return nil
+}
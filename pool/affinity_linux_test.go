@@ -0,0 +1,125 @@
+package pool_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// threadInfo records the CPU set and the nice level of the thread running it.
+type threadInfo struct {
+	mask *[16]uint64
+	nice *int
+}
+
+func (ti threadInfo) Job(context.Context) {
+	if getAffinity(ti.mask) != nil {
+		return
+	}
+
+	// The raw getpriority returns 20 - nice.
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+	if err == nil {
+		*ti.nice = 20 - prio
+	}
+}
+
+// getAffinity reads the CPU set of the calling thread.
+func getAffinity(mask *[16]uint64) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// allowedCPU returns a CPU the test may run on and the CPU set of only that CPU.
+func allowedCPU(t *testing.T) (int, [16]uint64) {
+	var mask [16]uint64
+	require.NoError(t, getAffinity(&mask))
+
+	for cpu := 0; cpu < len(mask)*64; cpu++ {
+		if mask[cpu/64]&(1<<(cpu%64)) != 0 {
+			var only [16]uint64
+			only[cpu/64] = 1 << (cpu % 64)
+			return cpu, only
+		}
+	}
+
+	t.Fatal("no CPU in the affinity mask")
+	return 0, mask
+}
+
+type threadInfoResponse struct {
+	threadInfo
+}
+
+func (ti threadInfoResponse) Job(ctx context.Context) pool.JobResponse[int] {
+	ti.threadInfo.Job(ctx)
+	return pool.JobResponse[int]{}
+}
+
+func TestPool_WithThreads(t *testing.T) {
+	t.Run("CPU set and nice level", func(t *testing.T) {
+		var mask [16]uint64
+		var nice int
+		cpu, want := allowedCPU(t)
+		// Every worker reports its errors from its own goroutine.
+		errs := make(chan error, 2)
+
+		workers := pool.New(2, pool.WithThreads(pool.ThreadConfig{
+			CPUs:    []int{cpu},
+			Nice:    5,
+			OnError: func(err error) { errs <- err },
+		}))
+		workers.Run(context.Background())
+		workers.Execute(threadInfo{&mask, &nice})
+		workers.Stop()
+		close(errs)
+
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, want, mask)
+		assert.Equal(t, 5, nice)
+	})
+
+	t.Run("Invalid CPU", func(t *testing.T) {
+		errs := make(chan error, 1)
+
+		workers := pool.New(1, pool.WithThreads(pool.ThreadConfig{
+			CPUs:    []int{-1},
+			OnError: func(err error) { errs <- err },
+		}))
+		workers.Run(context.Background())
+
+		// The worker runs its tasks anyway.
+		done := make(signal)
+		workers.Execute(done)
+		<-done
+		workers.Stop()
+
+		assert.ErrorContains(t, <-errs, "invalid CPU")
+	})
+}
+
+func TestNonBlocking_WithThreads(t *testing.T) {
+	var mask [16]uint64
+	var nice int
+	cpu, want := allowedCPU(t)
+
+	workers := pool.NewNonBlocking[int](1, pool.WithThreads(pool.ThreadConfig{CPUs: []int{cpu}}))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	resp := workers.Submit(context.Background(), threadInfoResponse{threadInfo{&mask, &nice}})
+	require.NoError(t, resp.Err)
+	assert.Equal(t, want, mask)
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package pool
+
+// setThread reports the thread settings which cannot be applied on this platform.
+func setThread(cfg ThreadConfig) error {
+	if len(cfg.CPUs) > 0 || cfg.Nice != 0 {
+		return ErrAffinityUnsupported
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBatchSize = fmt.Errorf("batch job returned a wrong number of responses")
+)
+
+// BatchRunner is an interface for a task which processes a batch of items at once.
+// It returns one response per item, in the order of the items.
+type BatchRunner[I, O any] interface {
+	Job(ctx context.Context, items []I) []JobResponse[O]
+}
+
+// BatchConfig keeps the settings of a Batcher.
+type BatchConfig struct {
+	// MaxSize is the number of items which makes a full batch. Default is 100.
+	MaxSize int
+	// MaxLinger is how long the first item of a batch waits for the others. Default is 10ms.
+	MaxLinger time.Duration
+}
+
+// Batcher coalesces the items submitted by many callers into batches and runs every batch
+// as a single task in a non-blocking pool. Each caller gets the response for its own item.
+// A batch is closed when it has MaxSize items or its first item has waited for MaxLinger.
+// While all workers are busy, the closed batches wait for a worker and the items keep coming
+// into the next batch.
+type Batcher[I, O any] struct {
+	cfg     BatchConfig
+	runner  BatchRunner[I, O]
+	workers *NonBlocking[[]JobResponse[O]]
+	items   chan *batchItem[I, O]
+	stop    chan struct{}
+	done    chan struct{}
+	flushes sync.WaitGroup
+}
+
+// batchItem is an item waiting for its batch.
+type batchItem[I, O any] struct {
+	ctx  context.Context
+	item I
+	resp chan JobResponse[O]
+}
+
+// batchTask runs a batch in a worker.
+type batchTask[I, O any] struct {
+	runner BatchRunner[I, O]
+	items  []I
+}
+
+func (t batchTask[I, O]) Job(ctx context.Context) JobResponse[[]JobResponse[O]] {
+	return JobResponse[[]JobResponse[O]]{Value: t.runner.Job(ctx, t.items)}
+}
+
+// NewBatcher creates a new Batcher running the batches in workersCnt workers.
+// The options configure the pool of the workers.
+func NewBatcher[I, O any](workersCnt int, cfg BatchConfig, runner BatchRunner[I, O], opts ...Option) *Batcher[I, O] {
+	if cfg.MaxSize < 1 {
+		cfg.MaxSize = 100
+	}
+	if cfg.MaxLinger <= 0 {
+		cfg.MaxLinger = 10 * time.Millisecond
+	}
+
+	return &Batcher[I, O]{
+		cfg:     cfg,
+		runner:  runner,
+		workers: NewNonBlocking[[]JobResponse[O]](workersCnt, opts...),
+		items:   make(chan *batchItem[I, O]),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run starts the workers and the collection of the batches.
+func (b *Batcher[I, O]) Run(ctx context.Context) {
+	b.workers.Run(ctx)
+	go b.collect(ctx)
+}
+
+// Stop runs the last batch and stops the workers.
+func (b *Batcher[I, O]) Stop() {
+	close(b.stop)
+	<-b.done
+
+	b.flushes.Wait()
+	b.workers.Stop()
+}
+
+// Stats returns the current statistic values of the pool running the batches.
+func (b *Batcher[I, O]) Stats() Stats {
+	return b.workers.Stats()
+}
+
+// Submit adds an item to the current batch and waits for its response.
+// If ctx is done before the batch runs, the item is left out of the batch.
+func (b *Batcher[I, O]) Submit(ctx context.Context, item I) JobResponse[O] {
+	it := &batchItem[I, O]{ctx: ctx, item: item, resp: make(chan JobResponse[O], 1)}
+
+	select {
+	case b.items <- it:
+	case <-b.done:
+		return JobResponse[O]{Err: ErrPoolStopped}
+	case <-ctx.Done():
+		return JobResponse[O]{Err: ctx.Err()}
+	}
+
+	select {
+	case resp := <-it.resp:
+		return resp
+	case <-ctx.Done():
+		return JobResponse[O]{Err: ctx.Err()}
+	}
+}
+
+// collect gathers the items into batches until the batcher is stopped.
+func (b *Batcher[I, O]) collect(ctx context.Context) {
+	defer close(b.done)
+
+	var batch []*batchItem[I, O]
+	// The linger timer is started by the first item, an idle batcher has no timer running.
+	var linger Timer
+	var lingered <-chan time.Time
+	for {
+		select {
+		case it := <-b.items:
+			if len(batch) == 0 {
+				if linger == nil {
+					linger = b.workers.cfg.clock.NewTimer(b.cfg.MaxLinger)
+					lingered = linger.C()
+				} else {
+					linger.Reset(b.cfg.MaxLinger)
+				}
+			}
+
+			batch = append(batch, it)
+			if len(batch) < b.cfg.MaxSize {
+				continue
+			}
+
+		case <-lingered:
+
+		case <-b.stop:
+			if linger != nil {
+				linger.Stop()
+			}
+			b.flush(ctx, batch)
+			return
+		}
+
+		if !linger.Stop() {
+			// Drains the tick which has come while the last item was being added.
+			select {
+			case <-linger.C():
+			default:
+			}
+		}
+		b.flush(ctx, batch)
+		batch = nil
+	}
+}
+
+// flush hands the batch over to a free worker without holding up the collection of the next one.
+// The responses are delivered to the callers after the worker has finished.
+func (b *Batcher[I, O]) flush(ctx context.Context, batch []*batchItem[I, O]) {
+	live := batch[:0]
+	for _, it := range batch {
+		if err := it.ctx.Err(); err != nil {
+			it.resp <- JobResponse[O]{Err: err}
+			continue
+		}
+
+		live = append(live, it)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	items := make([]I, len(live))
+	for i, it := range live {
+		items[i] = it.item
+	}
+
+	b.flushes.Add(1)
+	go func() {
+		defer b.flushes.Done()
+
+		req, err := b.workers.Acquire(ctx)
+		if err != nil {
+			deliver(live, JobResponse[[]JobResponse[O]]{Err: err})
+			return
+		}
+		defer req.Close()
+
+		req.Request <- batchTask[I, O]{b.runner, items}
+		deliver(live, <-req.Response)
+	}()
+}
+
+// deliver sends every item of a batch its response.
+func deliver[I, O any](batch []*batchItem[I, O], resp JobResponse[[]JobResponse[O]]) {
+	if resp.Err == nil && len(resp.Value) != len(batch) {
+		resp.Err = fmt.Errorf("%w: %d for %d items", ErrBatchSize, len(resp.Value), len(batch))
+	}
+
+	for i, it := range batch {
+		if resp.Err != nil {
+			it.resp <- JobResponse[O]{Err: resp.Err}
+			continue
+		}
+
+		it.resp <- resp.Value[i]
+	}
+}
@@ -0,0 +1,194 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+// upper converts a batch of strings to upper case and records the batch sizes.
+type upper struct {
+	sizes *[]int
+	mu    *sync.Mutex
+}
+
+func (u upper) Job(_ context.Context, items []string) []pool.JobResponse[string] {
+	u.mu.Lock()
+	*u.sizes = append(*u.sizes, len(items))
+	u.mu.Unlock()
+
+	resp := make([]pool.JobResponse[string], len(items))
+	for i, item := range items {
+		if item == "" {
+			resp[i].Err = errors.New("empty item")
+			continue
+		}
+		resp[i].Value = strings.ToUpper(item)
+	}
+
+	return resp
+}
+
+// short returns fewer responses than items.
+type short struct{}
+
+func (short) Job(context.Context, []string) []pool.JobResponse[string] {
+	return nil
+}
+
+func TestBatcher_Submit(t *testing.T) {
+	t.Run("Full batches", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxSize: 5, MaxLinger: time.Minute}, upper{&sizes, &mu})
+		batcher.Run(context.Background())
+
+		var wg sync.WaitGroup
+		for _, item := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+			wg.Add(1)
+			go func(item string) {
+				defer wg.Done()
+
+				resp := batcher.Submit(context.Background(), item)
+				assert.NoError(t, resp.Err)
+				assert.Equal(t, strings.ToUpper(item), resp.Value)
+			}(item)
+		}
+		wg.Wait()
+		batcher.Stop()
+
+		assert.Equal(t, []int{5, 5}, sizes)
+		assert.Equal(t, uint64(2), batcher.Stats().Completed)
+	})
+
+	t.Run("Linger closes a partial batch", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxSize: 100, MaxLinger: 10 * time.Millisecond}, upper{&sizes, &mu})
+		batcher.Run(context.Background())
+		defer batcher.Stop()
+
+		resp := batcher.Submit(context.Background(), "x")
+		require.NoError(t, resp.Err)
+		assert.Equal(t, "X", resp.Value)
+
+		resp = batcher.Submit(context.Background(), "")
+		assert.EqualError(t, resp.Err, "empty item")
+		assert.Equal(t, []int{1, 1}, sizes)
+	})
+
+	t.Run("Stop runs the last batch", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxLinger: time.Minute}, upper{&sizes, &mu})
+		batcher.Run(context.Background())
+
+		done := make(chan pool.JobResponse[string])
+		go func() {
+			done <- batcher.Submit(context.Background(), "last")
+		}()
+		// Lets the item get into the batch, which lingers for a minute.
+		time.Sleep(10 * time.Millisecond)
+		batcher.Stop()
+
+		resp := <-done
+		require.NoError(t, resp.Err)
+		assert.Equal(t, "LAST", resp.Value)
+		assert.ErrorIs(t, batcher.Submit(context.Background(), "late").Err, pool.ErrPoolStopped)
+	})
+
+	t.Run("Wrong number of responses", func(t *testing.T) {
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxLinger: time.Millisecond}, short{})
+		batcher.Run(context.Background())
+		defer batcher.Stop()
+
+		resp := batcher.Submit(context.Background(), "x")
+		assert.ErrorIs(t, resp.Err, pool.ErrBatchSize)
+	})
+
+	t.Run("Caller gives up", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxLinger: 50 * time.Millisecond}, upper{&sizes, &mu})
+		batcher.Run(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		resp := batcher.Submit(ctx, "x")
+		assert.ErrorIs(t, resp.Err, context.DeadlineExceeded)
+		batcher.Stop()
+
+		// The batch of the item which has gone is not run.
+		assert.Empty(t, sizes)
+	})
+
+	t.Run("Items keep coming while all workers are busy", func(t *testing.T) {
+		clock := pooltest.NewClock(time.Now())
+		runner := stalled{started: make(chan struct{}), release: make(chan struct{})}
+		batcher := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxLinger: time.Second}, runner, pool.WithClock(clock))
+		batcher.Run(context.Background())
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		submit := func(item string) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				resp := batcher.Submit(context.Background(), item)
+				assert.NoError(t, resp.Err)
+				assert.Equal(t, strings.ToUpper(item), resp.Value)
+			}()
+
+			// The item has started a batch, which lingers.
+			require.NoError(t, clock.WaitTimers(ctx, 1))
+			clock.Advance(time.Second)
+		}
+
+		// The first batch takes the only worker, the second one waits for it.
+		submit("first")
+		<-runner.started
+		submit("second")
+		submit("third")
+
+		close(runner.release)
+		wg.Wait()
+		batcher.Stop()
+		assert.Equal(t, uint64(3), batcher.Stats().Completed)
+	})
+}
+
+// stalled converts a batch of strings to upper case, the batch of "first" runs until it is released.
+type stalled struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s stalled) Job(_ context.Context, items []string) []pool.JobResponse[string] {
+	if items[0] == "first" {
+		close(s.started)
+		<-s.release
+	}
+
+	resp := make([]pool.JobResponse[string], len(items))
+	for i, item := range items {
+		resp[i].Value = strings.ToUpper(item)
+	}
+
+	return resp
+}
@@ -0,0 +1,295 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCircuitOpen = fmt.Errorf("circuit breaker is open")
+)
+
+// BreakerState is a state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all tasks run and counts their failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails all tasks fast with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe tasks run to find out whether the failures are over.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig keeps the settings of a circuit breaker.
+type BreakerConfig struct {
+	// FailureRate is the share of failed tasks in a window which opens the circuit. Default is 0.5.
+	FailureRate float64
+	// MinRequests is the number of finished tasks in a window needed to evaluate the failure rate.
+	// Default is 5.
+	MinRequests int
+	// Window is the period over which the failures are counted. Default is 10s.
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before it lets probe tasks run. Default is 5s.
+	OpenTimeout time.Duration
+	// Probes is the number of successful probe tasks which close the circuit. Default is 1.
+	Probes int
+	// IsFailure decides whether an error returned by a task counts as a failure.
+	// By default every error does.
+	IsFailure func(err error) bool
+	// OnStateChange is called when the circuit of a category changes its state.
+	OnStateChange func(category string, from, to BreakerState)
+	// Clock tells the time of the windows and the open circuits. Default is the system clock.
+	Clock Clock
+}
+
+// Breaker keeps a circuit for every category of tasks. A circuit opens when too many
+// tasks of its category fail, so the remaining ones fail fast instead of occupying the workers.
+type Breaker struct {
+	cfg      BreakerConfig
+	circuits map[string]*circuit
+	mu       sync.Mutex
+}
+
+// circuit keeps the state of one category of tasks.
+type circuit struct {
+	state       BreakerState
+	windowStart time.Time
+	total       int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	succeeded   int
+}
+
+// transition is a state change reported to the OnStateChange hook.
+type transition struct {
+	category string
+	from, to BreakerState
+}
+
+// NewBreaker creates a new circuit breaker.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.MinRequests < 1 {
+		cfg.MinRequests = 5
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	if cfg.Probes < 1 {
+		cfg.Probes = 1
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return err != nil }
+	}
+
+	return &Breaker{
+		cfg:      cfg,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// State returns the current state of the circuit of a category.
+func (b *Breaker) State(category string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[category]
+	if !ok {
+		return BreakerClosed
+	}
+
+	if c.state == BreakerOpen && b.cfg.Clock.Now().Sub(c.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+
+	return c.state
+}
+
+// Allow asks the circuit of a category whether a task may run.
+// If it may, the returned function has to be called with the outcome of the task.
+// Otherwise, Allow returns ErrCircuitOpen.
+func (b *Breaker) Allow(category string) (func(err error), error) {
+	b.mu.Lock()
+	c := b.circuit(category)
+	now := b.cfg.Clock.Now()
+
+	var changes []transition
+	if c.state == BreakerOpen && now.Sub(c.openedAt) >= b.cfg.OpenTimeout {
+		changes = append(changes, b.setState(category, c, BreakerHalfOpen, now))
+	}
+
+	switch {
+	case c.state == BreakerOpen,
+		c.state == BreakerHalfOpen && c.probes >= b.cfg.Probes:
+		b.mu.Unlock()
+		b.notify(changes)
+		return nil, ErrCircuitOpen
+
+	case c.state == BreakerHalfOpen:
+		c.probes++
+	}
+	probe := c.state == BreakerHalfOpen
+	b.mu.Unlock()
+	b.notify(changes)
+
+	return func(err error) {
+		b.done(category, probe, b.cfg.IsFailure(err))
+	}, nil
+}
+
+// Wrap returns a task which runs only while the circuit of the category lets it.
+// When the circuit is open, the task fails with ErrCircuitOpen without being run.
+func (b *Breaker) Wrap(category string, task FallibleRunner) FallibleRunner {
+	return breakerTask{b, category, task}
+}
+
+// WrapNonBlocking is Wrap for a task of a non-blocking pool.
+func WrapNonBlocking[T any](b *Breaker, category string, task NonBlockingRunner[T]) NonBlockingRunner[T] {
+	return breakerResponse[T]{b, category, task}
+}
+
+// done records the outcome of a task allowed by the circuit.
+func (b *Breaker) done(category string, probe bool, failed bool) {
+	b.mu.Lock()
+	c := b.circuit(category)
+	now := b.cfg.Clock.Now()
+
+	var changes []transition
+	switch {
+	case probe && c.state == BreakerHalfOpen:
+		c.probes--
+		if failed {
+			changes = append(changes, b.setState(category, c, BreakerOpen, now))
+			break
+		}
+
+		c.succeeded++
+		if c.succeeded >= b.cfg.Probes {
+			changes = append(changes, b.setState(category, c, BreakerClosed, now))
+		}
+
+	case c.state == BreakerClosed:
+		if now.Sub(c.windowStart) >= b.cfg.Window {
+			c.windowStart, c.total, c.failures = now, 0, 0
+		}
+
+		c.total++
+		if failed {
+			c.failures++
+		}
+
+		if c.total >= b.cfg.MinRequests && float64(c.failures)/float64(c.total) >= b.cfg.FailureRate {
+			changes = append(changes, b.setState(category, c, BreakerOpen, now))
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(changes)
+}
+
+func (b *Breaker) circuit(category string) *circuit {
+	c, ok := b.circuits[category]
+	if !ok {
+		c = &circuit{windowStart: b.cfg.Clock.Now()}
+		b.circuits[category] = c
+	}
+
+	return c
+}
+
+func (b *Breaker) setState(category string, c *circuit, state BreakerState, now time.Time) transition {
+	t := transition{category, c.state, state}
+
+	c.state = state
+	c.probes, c.succeeded = 0, 0
+	switch state {
+	case BreakerOpen:
+		c.openedAt = now
+	case BreakerClosed:
+		c.windowStart, c.total, c.failures = now, 0, 0
+	}
+
+	return t
+}
+
+// notify calls the hook outside of the lock, so it may use the breaker.
+func (b *Breaker) notify(changes []transition) {
+	if b.cfg.OnStateChange == nil {
+		return
+	}
+
+	for _, t := range changes {
+		b.cfg.OnStateChange(t.category, t.from, t.to)
+	}
+}
+
+// breakerTask is a FallibleRunner guarded by a circuit breaker.
+type breakerTask struct {
+	breaker  *Breaker
+	category string
+	task     FallibleRunner
+}
+
+func (t breakerTask) Job(ctx context.Context) error {
+	done, err := t.breaker.Allow(t.category)
+	if err != nil {
+		return err
+	}
+
+	err = t.task.Job(ctx)
+	done(err)
+
+	return err
+}
+
+func (t breakerTask) unwrap() any {
+	return t.task
+}
+
+// breakerResponse is a NonBlockingRunner[T] guarded by a circuit breaker.
+type breakerResponse[T any] struct {
+	breaker  *Breaker
+	category string
+	task     NonBlockingRunner[T]
+}
+
+func (t breakerResponse[T]) Job(ctx context.Context) JobResponse[T] {
+	done, err := t.breaker.Allow(t.category)
+	if err != nil {
+		return JobResponse[T]{Err: err}
+	}
+
+	resp := t.task.Job(ctx)
+	done(resp.Err)
+
+	return resp
+}
+
+func (t breakerResponse[T]) unwrap() any {
+	return t.task
+}
@@ -0,0 +1,154 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+var errHostDown = errors.New("host is down")
+
+type probe struct {
+	err error
+	ran *int
+	mu  *sync.Mutex
+}
+
+func (p probe) Job(context.Context) error {
+	p.mu.Lock()
+	*p.ran++
+	p.mu.Unlock()
+
+	return p.err
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("Failures open the circuit", func(t *testing.T) {
+		var changes []string
+		b := pool.NewBreaker(pool.BreakerConfig{
+			MinRequests: 3,
+			OpenTimeout: time.Hour,
+			OnStateChange: func(category string, from, to pool.BreakerState) {
+				changes = append(changes, category+": "+from.String()+" -> "+to.String())
+			},
+		})
+
+		var mu sync.Mutex
+		ran := 0
+		for i := 0; i < 10; i++ {
+			err := b.Wrap("dead.host", probe{errHostDown, &ran, &mu}).Job(context.Background())
+			if i < 3 {
+				assert.ErrorIs(t, err, errHostDown)
+			} else {
+				assert.ErrorIs(t, err, pool.ErrCircuitOpen)
+			}
+		}
+
+		assert.Equal(t, 3, ran)
+		assert.Equal(t, pool.BreakerOpen, b.State("dead.host"))
+		assert.Equal(t, pool.BreakerClosed, b.State("live.host"))
+		assert.Equal(t, []string{"dead.host: closed -> open"}, changes)
+	})
+
+	t.Run("Successful probe closes the circuit", func(t *testing.T) {
+		var changes []pool.BreakerState
+		clock := pooltest.NewClock(time.Now())
+		b := pool.NewBreaker(pool.BreakerConfig{
+			MinRequests: 1,
+			OpenTimeout: 10 * time.Millisecond,
+			Clock:       clock,
+			OnStateChange: func(_ string, _, to pool.BreakerState) {
+				changes = append(changes, to)
+			},
+		})
+
+		var mu sync.Mutex
+		ran := 0
+		_ = b.Wrap("host", probe{errHostDown, &ran, &mu}).Job(context.Background())
+		require.Equal(t, pool.BreakerOpen, b.State("host"))
+
+		clock.Advance(5 * time.Millisecond)
+		assert.Equal(t, pool.BreakerOpen, b.State("host"))
+		clock.Advance(5 * time.Millisecond)
+		assert.Equal(t, pool.BreakerHalfOpen, b.State("host"))
+
+		done, err := b.Allow("host")
+		require.NoError(t, err)
+
+		// Only one probe is let through at a time.
+		_, err = b.Allow("host")
+		assert.ErrorIs(t, err, pool.ErrCircuitOpen)
+
+		done(nil)
+		assert.Equal(t, pool.BreakerClosed, b.State("host"))
+		assert.Equal(t, []pool.BreakerState{pool.BreakerOpen, pool.BreakerHalfOpen, pool.BreakerClosed}, changes)
+	})
+
+	t.Run("Failed probe opens the circuit again", func(t *testing.T) {
+		clock := pooltest.NewClock(time.Now())
+		b := pool.NewBreaker(pool.BreakerConfig{
+			MinRequests: 1,
+			OpenTimeout: 10 * time.Millisecond,
+			Clock:       clock,
+		})
+
+		done, err := b.Allow("host")
+		require.NoError(t, err)
+		done(errHostDown)
+
+		clock.Advance(20 * time.Millisecond)
+		done, err = b.Allow("host")
+		require.NoError(t, err)
+		done(errHostDown)
+
+		assert.Equal(t, pool.BreakerOpen, b.State("host"))
+	})
+
+	t.Run("Non-blocking tasks", func(t *testing.T) {
+		b := pool.NewBreaker(pool.BreakerConfig{MinRequests: 2, OpenTimeout: time.Hour})
+
+		workers := pool.NewNonBlocking[string](2)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		for i := 0; i < 5; i++ {
+			resp := workers.Submit(context.Background(), pool.WrapNonBlocking[string](b, "host", failing{errHostDown}))
+			if i < 2 {
+				assert.ErrorIs(t, resp.Err, errHostDown)
+			} else {
+				assert.ErrorIs(t, resp.Err, pool.ErrCircuitOpen)
+			}
+		}
+	})
+}
+
+func TestPool_Fallible(t *testing.T) {
+	t.Run("Failed tasks are counted", func(t *testing.T) {
+		var mu sync.Mutex
+		ran := 0
+
+		workers := pool.New(3)
+		workers.Run(context.Background())
+
+		for i := 0; i < 10; i++ {
+			var err error
+			if i%5 == 0 {
+				err = errHostDown
+			}
+			workers.Execute(pool.Fallible(probe{err, &ran, &mu}))
+		}
+		workers.Stop()
+
+		assert.Equal(t, 10, ran)
+		assert.Equal(t, uint64(10), workers.Stats().Completed)
+		assert.Equal(t, uint64(2), workers.Stats().Failed)
+	})
+}
@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes the timers of a pool, its queues and its watchdog (see WithClock).
+// The pooltest package has a fake clock which tests move forward by hand.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a timer of a Clock, it behaves like time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker is a ticker of a Clock, it behaves like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock returns the clock of the time package, pools use it by default.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// withTimeout is context.WithTimeout with the timer of clock.
+func withTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(systemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+
+	t := &timeoutCtx{
+		Context:  ctx,
+		deadline: clock.Now().Add(d),
+		done:     make(chan struct{}),
+	}
+	timer := clock.NewTimer(d)
+	go func() {
+		defer timer.Stop()
+
+		select {
+		case <-timer.C():
+			t.cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+			t.cancel(ctx.Err())
+		case <-t.done:
+		}
+	}()
+
+	return t, func() { t.cancel(context.Canceled) }
+}
+
+// timeoutCtx is a context done when the timer of a Clock fires.
+type timeoutCtx struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+	err      error
+	mu       sync.Mutex
+}
+
+func (t *timeoutCtx) Deadline() (time.Time, bool) {
+	return t.deadline, true
+}
+
+func (t *timeoutCtx) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *timeoutCtx) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// cancel makes the context done with the error unless it is done already.
+func (t *timeoutCtx) cancel(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err == nil {
+		t.err = err
+		close(t.done)
+	}
+}
@@ -0,0 +1,58 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+func TestSystemClock(t *testing.T) {
+	clock := pool.SystemClock()
+
+	start := clock.Now()
+	timer := clock.NewTimer(time.Millisecond)
+	fired := <-timer.C()
+	assert.False(t, fired.Before(start.Add(time.Millisecond)))
+	assert.False(t, timer.Stop())
+
+	assert.False(t, timer.Reset(time.Hour))
+	assert.True(t, timer.Stop())
+
+	ticker := clock.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	first, second := <-ticker.C(), <-ticker.C()
+	assert.True(t, second.After(first))
+}
+
+func TestBatcher_WithClock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var sizes []int
+	var mu sync.Mutex
+	clock := pooltest.NewClock(time.Now())
+	b := pool.NewBatcher[string, string](1, pool.BatchConfig{MaxSize: 10, MaxLinger: time.Minute},
+		upper{&sizes, &mu}, pool.WithClock(clock))
+	b.Run(context.Background())
+	defer b.Stop()
+
+	resp := make(chan pool.JobResponse[string], 1)
+	go func() { resp <- b.Submit(ctx, "a") }()
+
+	// The batch lingers until the clock is advanced.
+	require.NoError(t, clock.WaitTimers(ctx, 1))
+	mu.Lock()
+	assert.Empty(t, sizes)
+	mu.Unlock()
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, "A", (<-resp).Value)
+	assert.Equal(t, []int{1}, sizes)
+}
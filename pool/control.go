@@ -0,0 +1,178 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrPoolDraining = fmt.Errorf("pool draining")
+	ErrPoolSize     = fmt.Errorf("number of workers is less than 1")
+)
+
+// drainInterval is the time between the checks of a draining pool.
+const drainInterval = 10 * time.Millisecond
+
+// Pause stops the workers from taking new tasks, the running tasks go on.
+// The callers wait for a worker until the pool is resumed.
+func (c *core) Pause() {
+	c.ctlMu.Lock()
+	defer c.ctlMu.Unlock()
+
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+		c.nudge()
+	}
+}
+
+// Resume lets the workers of a paused pool take tasks again and makes a drained pool accept tasks.
+func (c *core) Resume() {
+	c.ctlMu.Lock()
+	defer c.ctlMu.Unlock()
+
+	c.draining = false
+	c.unpause()
+}
+
+// Paused reports whether the pool is paused.
+func (c *core) Paused() bool {
+	c.ctlMu.Lock()
+	defer c.ctlMu.Unlock()
+
+	return c.paused
+}
+
+// Drain stops accepting tasks and waits until the queued and running tasks are finished or ctx is done.
+// The callers adding tasks meanwhile get ErrPoolDraining. A paused pool is resumed to run
+// the queued tasks. Resume makes the pool accept tasks again.
+func (c *core) Drain(ctx context.Context) error {
+	c.ctlMu.Lock()
+	c.draining = true
+	c.unpause()
+	c.ctlMu.Unlock()
+
+	ticker := c.cfg.clock.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for !c.idleNow() {
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Draining reports whether the pool is draining (see Drain).
+func (c *core) Draining() bool {
+	c.ctlMu.Lock()
+	defer c.ctlMu.Unlock()
+
+	return c.draining
+}
+
+// resize changes the number of workers and wakes the waiting ones, so the extra workers exit.
+func (c *core) resize(n int) error {
+	if n < 1 {
+		return ErrPoolSize
+	}
+
+	c.size.Store(int64(n))
+	c.ctlMu.Lock()
+	c.nudge()
+	c.ctlMu.Unlock()
+
+	return nil
+}
+
+// workers returns the current number of workers of the pool.
+func (c *core) workers() int {
+	return int(c.size.Load())
+}
+
+// accepting returns ErrPoolStopped or ErrPoolDraining if the pool does not accept tasks.
+func (c *core) accepting() error {
+	if c.phase.Load() == phaseStopped {
+		return ErrPoolStopped
+	}
+	if c.Draining() {
+		return ErrPoolDraining
+	}
+
+	return nil
+}
+
+// halt marks the pool stopped and wakes up the callers waiting for a worker.
+func (c *core) halt() {
+	if c.phase.Swap(phaseStopped) != phaseStopped {
+		close(c.stopped)
+		c.unpublish()
+	}
+}
+
+// awaitResume waits while the pool is paused.
+func (c *core) awaitResume(ctx context.Context) error {
+	c.ctlMu.Lock()
+	resumed := c.resumed
+	c.ctlMu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// nudged returns a channel which is closed when the waiting workers have to check
+// whether the pool is paused or has too many workers.
+func (c *core) nudged() <-chan struct{} {
+	c.ctlMu.Lock()
+	defer c.ctlMu.Unlock()
+
+	return c.wakeup
+}
+
+// dismiss counts a worker out if the pool has more workers than it should.
+// The worker has to exit without a successor.
+func (c *core) dismiss() bool {
+	for {
+		n := c.live.Load()
+		if n <= c.size.Load() {
+			return false
+		}
+
+		if c.live.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+// nudge wakes the waiting workers, ctlMu is held by the caller.
+func (c *core) nudge() {
+	close(c.wakeup)
+	c.wakeup = make(chan struct{})
+}
+
+// unpause resumes a paused pool, ctlMu is held by the caller.
+func (c *core) unpause() {
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+}
+
+// idleNow reports whether the pool has no queued or running tasks.
+func (c *core) idleNow() bool {
+	if c.running.Load() > 0 {
+		return false
+	}
+	if c.queue != nil && c.queue.len() > 0 || c.sched != nil && c.sched.len() > 0 {
+		return false
+	}
+
+	return c.tracked.Load() == 0
+}
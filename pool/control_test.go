@@ -0,0 +1,147 @@
+package pool_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+func TestPool_Resize(t *testing.T) {
+	for name, opts := range map[string][]pool.Option{
+		"Direct":    nil,
+		"Scheduled": {pool.WithTenants(pool.TenantConfig{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			workers := pool.New(2, opts...)
+			workers.Run(context.Background())
+			defer workers.Stop()
+
+			require.NoError(t, workers.Resize(4))
+			assert.Equal(t, 4, workers.Stats().Workers)
+			assert.Equal(t, 4, workers.Stats().Live)
+
+			// A busy worker exits after its task.
+			gate := newBlocked()
+			workers.Execute(gate)
+			<-gate.started
+
+			require.NoError(t, workers.Resize(1))
+			require.Eventually(t, func() bool { return workers.Stats().Live == 1 }, time.Second, time.Millisecond)
+			assert.Equal(t, 0, workers.Stats().Idle)
+
+			close(gate.release)
+			require.NoError(t, workers.Execute(signal(make(chan struct{}))).Wait(context.Background()))
+			assert.Equal(t, 1, workers.Stats().Live)
+
+			assert.ErrorIs(t, workers.Resize(0), pool.ErrPoolSize)
+		})
+	}
+}
+
+func TestPool_Pause(t *testing.T) {
+	workers := pool.New(2, pool.WithTenants(pool.TenantConfig{}))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	workers.Pause()
+	assert.True(t, workers.Paused())
+
+	var cnt atomic.Int64
+	h := workers.Execute(counted{&cnt})
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, pool.TaskQueued, h.Status())
+
+	workers.Resume()
+	assert.False(t, workers.Paused())
+	require.NoError(t, h.Wait(context.Background()))
+	assert.Equal(t, int64(1), cnt.Load())
+}
+
+func TestPool_Drain(t *testing.T) {
+	workers := pool.New(1, pool.WithTenants(pool.TenantConfig{}))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	gate := newBlocked()
+	workers.Execute(gate)
+	<-gate.started
+	var cnt atomic.Int64
+	queued := workers.Execute(counted{&cnt})
+
+	drained := make(chan error)
+	go func() {
+		drained <- workers.Drain(context.Background())
+	}()
+	require.Eventually(t, workers.Draining, time.Second, time.Millisecond)
+
+	_, err := workers.Start(context.Background(), counted{&cnt})
+	assert.ErrorIs(t, err, pool.ErrPoolDraining)
+
+	close(gate.release)
+	require.NoError(t, <-drained)
+	assert.Equal(t, pool.TaskDone, queued.Status())
+	assert.Equal(t, int64(1), cnt.Load())
+
+	t.Run("Timeout", func(t *testing.T) {
+		gate := newBlocked()
+		workers.Resume()
+		workers.Execute(gate)
+		<-gate.started
+		defer close(gate.release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, workers.Drain(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("Execute drops the task", func(t *testing.T) {
+		var rejected []error
+		workers := pool.New(1, pool.WithRejectHandler(func(_ any, err error) {
+			rejected = append(rejected, err)
+		}))
+		workers.Run(context.Background())
+		require.NoError(t, workers.Drain(context.Background()))
+
+		var cnt atomic.Int64
+		h := workers.Execute(counted{&cnt})
+		assert.Equal(t, pool.TaskFailed, h.Status())
+		assert.ErrorIs(t, h.Err(), pool.ErrPoolDraining)
+
+		workers.Stop()
+		h = workers.Execute(counted{&cnt})
+		assert.ErrorIs(t, h.Err(), pool.ErrPoolStopped)
+
+		assert.Equal(t, []error{pool.ErrPoolDraining, pool.ErrPoolStopped}, rejected)
+		assert.Equal(t, int64(0), cnt.Load())
+	})
+}
+
+func TestNonBlocking_Resize(t *testing.T) {
+	workers := pool.NewNonBlocking[int](1)
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	require.NoError(t, workers.Resize(3))
+	require.Equal(t, 3, workers.Stats().Live)
+
+	require.NoError(t, workers.Resize(2))
+	require.Eventually(t, func() bool { return workers.Stats().Live == 2 }, time.Second, time.Millisecond)
+
+	workers.Pause()
+	h := workers.Start(context.Background(), workerOfResponse{})
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, pool.TaskQueued, h.Status())
+
+	workers.Resume()
+	require.NoError(t, h.Wait(context.Background()))
+
+	require.NoError(t, workers.Drain(context.Background()))
+	resp := workers.Submit(context.Background(), workerOfResponse{})
+	assert.ErrorIs(t, resp.Err, pool.ErrPoolDraining)
+}
@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// core carries the state shared by the blocking and non-blocking pools.
+type core struct {
+	cfg       config
+	size      atomic.Int64
+	capacity  *semaphore
+	memory    *semaphore
+	gate      *gate
+	queue     *queue
+	sched     scheduler
+	idle      atomic.Int64
+	running   atomic.Int64
+	latency   atomic.Int64
+	completed atomic.Uint64
+	failed    atomic.Uint64
+	rejected  atomic.Uint64
+	workerSeq atomic.Int64
+	live      atomic.Int64
+	waiting   atomic.Int64
+	handleSeq atomic.Uint64
+	// handles keeps the queued and running tasks by their IDs, tracked counts them.
+	handles  sync.Map
+	tracked  atomic.Int64
+	watchdog *watchdog
+	phase    atomic.Int32
+	// stopped is closed by Stop, it wakes up the callers waiting for a worker.
+	stopped chan struct{}
+	// saturated is the time in nanoseconds since every worker has been busy, zero after a worker
+	// has found nothing to do.
+	saturated atomic.Int64
+	paused    bool
+	draining  bool
+	// resumed is closed while the pool is not paused.
+	resumed chan struct{}
+	// wakeup is closed and replaced when the waiting workers have to check the pool settings.
+	wakeup chan struct{}
+	ctlMu  sync.Mutex
+}
+
+func newCore(workersCnt int, opts []Option) *core {
+	c := &core{
+		cfg:     newConfig(opts),
+		resumed: make(chan struct{}),
+		wakeup:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	close(c.resumed)
+	c.size.Store(int64(workersCnt))
+	if c.cfg.clock == nil {
+		c.cfg.clock = systemClock{}
+	}
+
+	if c.cfg.capacity > 0 {
+		c.capacity = newSemaphore(c.cfg.capacity)
+	}
+	if c.cfg.memoryBudget > 0 {
+		c.memory = newSemaphore(c.cfg.memoryBudget)
+	}
+	if c.cfg.limiter != nil {
+		c.gate = newGate(c.cfg.limiter)
+	}
+	if c.cfg.name != "" {
+		publish(c.cfg.name, c)
+	}
+	switch {
+	case c.cfg.queue != nil:
+		c.queue = newQueue(*c.cfg.queue, c.cfg.deadline != nil, c.cfg.clock, c.retryAfter)
+	case c.cfg.deadline != nil:
+		c.queue = newQueue(QueueConfig{}, true, c.cfg.clock, c.retryAfter)
+	}
+
+	return c
+}
+
+// enter blocks a worker until the concurrency limit lets it take a task.
+func (c *core) enter(ctx context.Context) error {
+	if c.gate == nil {
+		return nil
+	}
+
+	return c.gate.enter(ctx)
+}
+
+// leave lets another worker take a task.
+func (c *core) leave() {
+	if c.gate != nil {
+		c.gate.leave()
+	}
+}
+
+// admit waits until the task fits into the pool budgets.
+// The returned function gives the taken resources back when the task is finished.
+func (c *core) admit(ctx context.Context, task any) (func(), error) {
+	release, err := c.admitMemory(ctx, task)
+	if err != nil {
+		c.reject(task, err)
+		return nil, err
+	}
+
+	if c.capacity == nil {
+		return release, nil
+	}
+
+	w := weightOf(task, c.cfg.capacity)
+	if err := c.capacity.acquire(ctx, w); err != nil {
+		release()
+		c.reject(task, err)
+		return nil, err
+	}
+
+	return func() {
+		c.capacity.release(w)
+		release()
+	}, nil
+}
+
+// execute runs the job of an admitted task and records its outcome.
+// The job of a named pool runs with the profiler labels of the task (see WithName).
+func (c *core) execute(ctx context.Context, task any, job func(ctx context.Context) error) {
+	if c.cfg.taskHook != nil {
+		if done := c.cfg.taskHook(ctx, innermost(task)); done != nil {
+			defer done()
+		}
+	}
+	c.occupy()
+	start := c.cfg.clock.Now()
+	err := c.label(ctx, task, job)
+	latency := c.cfg.clock.Now().Sub(start)
+	c.running.Add(-1)
+
+	// Keeps an exponential moving average of the latency, a lost concurrent update does no harm.
+	avg := c.latency.Load()
+	c.latency.Store(avg + (int64(latency)-avg)/8)
+
+	c.completed.Add(1)
+	if err != nil {
+		c.failed.Add(1)
+	}
+	if c.gate != nil {
+		c.gate.observe(latency, err)
+	}
+}
+
+// retryAfter estimates the time the workers need to serve the queued callers.
+func (c *core) retryAfter(queued int) time.Duration {
+	n := c.workers()
+	if n < 1 {
+		return 0
+	}
+
+	return time.Duration(c.latency.Load()) * time.Duration(queued+1) / time.Duration(n)
+}
+
+// reject reports a task skipped by the pool.
+func (c *core) reject(task any, err error) {
+	c.rejected.Add(1)
+	if c.cfg.onReject != nil {
+		c.cfg.onReject(task, err)
+	}
+}
+
+// taskAs finds an optional interface implemented by a task or by a task it wraps.
+func taskAs[I any](task any) (I, bool) {
+	for task != nil {
+		if t, ok := task.(I); ok {
+			return t, true
+		}
+
+		w, ok := task.(interface{ unwrap() any })
+		if !ok {
+			break
+		}
+		task = w.unwrap()
+	}
+
+	var zero I
+	return zero, false
+}
+
+// innermost returns the task added to the pool, looking through the wrappers of the pool.
+func innermost(task any) any {
+	for {
+		w, ok := task.(interface{ unwrap() any })
+		if !ok {
+			return task
+		}
+		task = w.unwrap()
+	}
+}
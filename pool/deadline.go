@@ -0,0 +1,198 @@
+package pool
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDeadlineExceeded is returned for a task skipped because its deadline had passed
+	// or could not be met before the task started. It matches context.DeadlineExceeded.
+	ErrDeadlineExceeded = fmt.Errorf("%w before the task started", context.DeadlineExceeded)
+)
+
+// DurationEstimator is an optional interface for a task which declares how long it runs.
+// The earliest-deadline-first scheduling skips a task whose deadline is closer than its duration.
+type DurationEstimator interface {
+	DurationEstimate() time.Duration
+}
+
+// DeadlineConfig keeps the settings of the earliest-deadline-first scheduling.
+type DeadlineConfig struct {
+	// MaxQueued bounds the number of queued tasks of the blocking pool, 0 means no bound.
+	// Execute and Submit wait while the queue is full.
+	MaxQueued int
+}
+
+// lateness returns ErrDeadlineExceeded if a task with ctx cannot finish in time,
+// or the context error if ctx is done.
+func lateness(ctx context.Context, task any, now time.Time) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx.Err()
+	}
+
+	left := deadline.Sub(now)
+	if t, ok := taskAs[DurationEstimator](task); left <= 0 || (ok && left < t.DurationEstimate()) {
+		return ErrDeadlineExceeded
+	}
+
+	return ctx.Err()
+}
+
+// earlier reports whether a context has an earlier deadline than another one.
+// A context without a deadline is later than any with it.
+func earlier(a, b context.Context) bool {
+	da, okA := a.Deadline()
+	db, okB := b.Deadline()
+
+	switch {
+	case okA && okB:
+		return da.Before(db)
+	default:
+		return okA && !okB
+	}
+}
+
+// deadlineQueue serves the tasks of the blocking pool in the order of their context deadlines
+// and skips those which cannot finish in time.
+type deadlineQueue struct {
+	cfg     DeadlineConfig
+	jobs    jobHeap
+	seq     uint64
+	changed chan struct{}
+	closed  bool
+	drop    func(j *job, err error)
+	clock   Clock
+	mu      sync.Mutex
+}
+
+func newDeadlineQueue(cfg DeadlineConfig, clock Clock, drop func(j *job, err error)) *deadlineQueue {
+	return &deadlineQueue{
+		cfg:     cfg,
+		changed: make(chan struct{}),
+		drop:    drop,
+		clock:   clock,
+	}
+}
+
+func (q *deadlineQueue) push(ctx context.Context, j *job) error {
+	q.mu.Lock()
+	for q.cfg.MaxQueued > 0 && len(q.jobs) >= q.cfg.MaxQueued && !q.closed {
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrPoolStopped
+	}
+
+	q.seq++
+	j.seq = q.seq
+	heap.Push(&q.jobs, j)
+	q.notify()
+
+	return nil
+}
+
+func (q *deadlineQueue) wait() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.jobs) == 0 && !q.closed {
+		changed := q.changed
+		q.mu.Unlock()
+		<-changed
+		q.mu.Lock()
+	}
+
+	return len(q.jobs) > 0
+}
+
+func (q *deadlineQueue) pop() *job {
+	for {
+		q.mu.Lock()
+		if len(q.jobs) == 0 {
+			q.mu.Unlock()
+			return nil
+		}
+
+		j := heap.Pop(&q.jobs).(*job)
+		q.notify()
+		q.mu.Unlock()
+
+		if err := lateness(j.ctx, j.task, q.clock.Now()); err != nil {
+			q.drop(j, err)
+			continue
+		}
+
+		return j
+	}
+}
+
+func (q *deadlineQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.notify()
+}
+
+func (q *deadlineQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.jobs)
+}
+
+func (q *deadlineQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// jobHeap orders jobs by deadline, jobs with the same deadline keep the order they came in.
+type jobHeap []*job
+
+func (h jobHeap) Len() int {
+	return len(h)
+}
+
+func (h jobHeap) Less(i, j int) bool {
+	if earlier(h[i].ctx, h[j].ctx) {
+		return true
+	}
+	if earlier(h[j].ctx, h[i].ctx) {
+		return false
+	}
+
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *jobHeap) Push(x any) {
+	*h = append(*h, x.(*job))
+}
+
+func (h *jobHeap) Pop() any {
+	old := *h
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+
+	return j
+}
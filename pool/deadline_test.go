@@ -0,0 +1,164 @@
+package pool_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+type deadlineTask struct {
+	name     string
+	duration time.Duration
+	log      *journal
+}
+
+func (d deadlineTask) DurationEstimate() time.Duration {
+	return d.duration
+}
+
+func (d deadlineTask) Job(context.Context) {
+	d.log.mu.Lock()
+	d.log.order = append(d.log.order, d.name)
+	d.log.mu.Unlock()
+}
+
+func (d deadlineTask) Response(ctx context.Context) pool.NonBlockingRunner[string] {
+	return deadlineResponse{d}
+}
+
+type deadlineResponse struct {
+	deadlineTask
+}
+
+func (d deadlineResponse) Job(ctx context.Context) pool.JobResponse[string] {
+	d.deadlineTask.Job(ctx)
+	return pool.JobResponse[string]{Value: d.name}
+}
+
+func withTimeout(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func TestPool_WithDeadlineScheduling(t *testing.T) {
+	t.Run("Earliest deadline first", func(t *testing.T) {
+		var log journal
+
+		workers := pool.New(1, pool.WithDeadlineScheduling(pool.DeadlineConfig{}))
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		workers.Execute(gate)
+		<-gate.started
+
+		require.NoError(t, workers.Submit(withTimeout(t, 3*time.Second), deadlineTask{"3", 0, &log}))
+		require.NoError(t, workers.Submit(context.Background(), deadlineTask{"-", 0, &log}))
+		require.NoError(t, workers.Submit(withTimeout(t, time.Second), deadlineTask{"1", 0, &log}))
+		require.NoError(t, workers.Submit(withTimeout(t, 2*time.Second), deadlineTask{"2", 0, &log}))
+
+		close(gate.release)
+		workers.Stop()
+
+		assert.Equal(t, "123-", strings.Join(log.order, ""))
+	})
+
+	t.Run("Late tasks are skipped", func(t *testing.T) {
+		var log journal
+		var mu sync.Mutex
+		var rejected []error
+
+		workers := pool.New(1,
+			pool.WithDeadlineScheduling(pool.DeadlineConfig{}),
+			pool.WithRejectHandler(func(task any, err error) {
+				mu.Lock()
+				rejected = append(rejected, err)
+				mu.Unlock()
+			}),
+		)
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		workers.Execute(gate)
+		<-gate.started
+
+		require.NoError(t, workers.Submit(withTimeout(t, 10*time.Millisecond), deadlineTask{"expired", 0, &log}))
+		require.NoError(t, workers.Submit(withTimeout(t, time.Minute), deadlineTask{"too long", time.Hour, &log}))
+		require.NoError(t, workers.Submit(withTimeout(t, time.Minute), deadlineTask{"in time", time.Millisecond, &log}))
+		time.Sleep(20 * time.Millisecond)
+
+		close(gate.release)
+		workers.Stop()
+
+		assert.Equal(t, []string{"in time"}, log.order)
+		require.Len(t, rejected, 2)
+		for _, err := range rejected {
+			assert.ErrorIs(t, err, pool.ErrDeadlineExceeded)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestNonBlocking_WithDeadlineScheduling(t *testing.T) {
+	t.Run("Earliest deadline first", func(t *testing.T) {
+		var log journal
+
+		workers := pool.NewNonBlocking[string](1, pool.WithDeadlineScheduling(pool.DeadlineConfig{}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for _, n := range []int{3, 1, 2} {
+			ctx := withTimeout(t, time.Duration(n)*time.Second)
+			task := deadlineResponse{deadlineTask{name: strings.Repeat("*", n), log: &log}}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp := workers.Submit(ctx, task)
+				assert.NoError(t, resp.Err)
+			}()
+		}
+		require.Eventually(t, func() bool { return workers.Stats().Queued == 3 }, time.Second, time.Millisecond)
+
+		busy.Close()
+		wg.Wait()
+
+		assert.Equal(t, []string{"*", "**", "***"}, log.order)
+	})
+
+	t.Run("Caller who cannot make it is shed", func(t *testing.T) {
+		var log journal
+
+		workers := pool.NewNonBlocking[string](1, pool.WithDeadlineScheduling(pool.DeadlineConfig{}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		shed := make(chan pool.JobResponse[string])
+		go func() {
+			task := deadlineResponse{deadlineTask{"too long", time.Hour, &log}}
+			shed <- workers.Submit(withTimeout(t, time.Minute), task)
+		}()
+		require.Eventually(t, func() bool { return workers.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		busy.Close()
+		resp := <-shed
+		assert.ErrorIs(t, resp.Err, pool.ErrDeadlineExceeded)
+		assert.Empty(t, log.order)
+		assert.Equal(t, uint64(1), workers.Stats().Rejected)
+	})
+}
@@ -0,0 +1,267 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+)
+
+// Tenanter is an optional interface for a task which belongs to a tenant.
+// Tasks which do not implement it belong to the tenant with the empty name.
+type Tenanter interface {
+	Tenant() string
+}
+
+// TenantConfig keeps the settings of fair queuing across tenants.
+type TenantConfig struct {
+	// Weights keeps the share of the workers every tenant gets when all of them are busy.
+	Weights map[string]int
+	// DefaultWeight is the share of a tenant missing in Weights. Default is 1.
+	DefaultWeight int
+	// MaxQueued bounds the number of queued tasks of every tenant, 0 means no bound.
+	// Execute and Submit wait while the queue of the tenant is full.
+	MaxQueued int
+}
+
+// TenantStats keeps statistic values about the tasks of a tenant.
+type TenantStats struct {
+	// Weight is the share of the workers of the tenant.
+	Weight int
+	// Queued is the number of tasks waiting for a worker.
+	Queued int
+	// Running is the number of tasks being executed now.
+	Running int
+	// Completed is the number of finished tasks.
+	Completed uint64
+}
+
+// scheduler orders the tasks waiting for a worker of the blocking pool.
+type scheduler interface {
+	// push adds a job, waiting while there is no room for it.
+	push(ctx context.Context, j *job) error
+	// wait waits until there is a queued job. It returns false when the scheduler is closed and drained.
+	wait() bool
+	// pop takes the next job, it returns nil if there is none.
+	pop() *job
+	// close makes push fail with ErrPoolStopped, the queued jobs are still popped.
+	close()
+	// len returns the number of queued jobs.
+	len() int
+}
+
+// tenant keeps the queued tasks and the counters of a tenant.
+type tenant struct {
+	name      string
+	weight    int
+	deficit   int
+	served    bool
+	jobs      list.List
+	running   int
+	completed uint64
+	elem      *list.Element
+}
+
+// fairQueue serves tenants with deficit round robin, so every tenant gets
+// its weighted share of the workers regardless of the bursts of the others.
+type fairQueue struct {
+	cfg     TenantConfig
+	tenants map[string]*tenant
+	active  list.List
+	queued  int
+	changed chan struct{}
+	closed  bool
+	mu      sync.Mutex
+}
+
+func newFairQueue(cfg TenantConfig) *fairQueue {
+	if cfg.DefaultWeight < 1 {
+		cfg.DefaultWeight = 1
+	}
+
+	return &fairQueue{
+		cfg:     cfg,
+		tenants: make(map[string]*tenant),
+		changed: make(chan struct{}),
+	}
+}
+
+func (q *fairQueue) push(ctx context.Context, j *job) error {
+	name := ""
+	if t, ok := taskAs[Tenanter](j.task); ok {
+		name = t.Tenant()
+	}
+
+	q.mu.Lock()
+	for {
+		if q.closed {
+			q.mu.Unlock()
+			return ErrPoolStopped
+		}
+
+		t := q.tenant(name)
+		if q.cfg.MaxQueued < 1 || t.jobs.Len() < q.cfg.MaxQueued {
+			j.done = func() { q.done(t) }
+			t.jobs.PushBack(j)
+			if t.elem == nil {
+				t.elem = q.active.PushBack(t)
+			}
+			q.queued++
+			q.notify()
+			q.mu.Unlock()
+			return nil
+		}
+
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+}
+
+func (q *fairQueue) wait() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.active.Len() == 0 && !q.closed {
+		changed := q.changed
+		q.mu.Unlock()
+		<-changed
+		q.mu.Lock()
+	}
+
+	return q.active.Len() > 0
+}
+
+func (q *fairQueue) pop() *job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active.Len() == 0 {
+		return nil
+	}
+
+	skipped := 0
+	for {
+		elem := q.active.Front()
+		t := elem.Value.(*tenant)
+		if !t.served {
+			// The tenant starts its turn in the round.
+			t.deficit += t.weight
+			t.served = true
+		}
+
+		next := t.jobs.Front()
+		j := next.Value.(*job)
+		if j.cost() > t.deficit {
+			// The turn is over, the deficit is kept for the next round.
+			t.served = false
+			q.active.MoveToBack(elem)
+			if skipped++; skipped == q.active.Len() {
+				// No tenant can afford its next task in this round.
+				q.skipRounds()
+				skipped = 0
+			}
+			continue
+		}
+
+		t.deficit -= j.cost()
+		t.jobs.Remove(next)
+		t.running++
+		q.queued--
+		if t.jobs.Len() == 0 {
+			t.deficit, t.served = 0, false
+			q.active.Remove(elem)
+			t.elem = nil
+		}
+		q.notify()
+
+		return j
+	}
+}
+
+// skipRounds adds at once the deficit the tenants would get in the rounds where none of them
+// can afford its next task, so a heavy task does not make pop spin for a long time.
+// All tenants have finished their turns, the next round makes the first of them able to pay.
+func (q *fairQueue) skipRounds() {
+	rounds := math.MaxInt
+	for elem := q.active.Front(); elem != nil; elem = elem.Next() {
+		t := elem.Value.(*tenant)
+		short := t.jobs.Front().Value.(*job).cost() - t.deficit
+		if n := (short-1)/t.weight + 1; n < rounds {
+			rounds = n
+		}
+	}
+
+	for elem := q.active.Front(); elem != nil; elem = elem.Next() {
+		t := elem.Value.(*tenant)
+		t.deficit += (rounds - 1) * t.weight
+	}
+}
+
+func (q *fairQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.notify()
+}
+
+func (q *fairQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.queued
+}
+
+// done records a finished job of a tenant.
+func (q *fairQueue) done(t *tenant) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	t.running--
+	t.completed++
+}
+
+// stats returns the counters of every tenant.
+func (q *fairQueue) stats() map[string]TenantStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := make(map[string]TenantStats, len(q.tenants))
+	for name, t := range q.tenants {
+		s[name] = TenantStats{
+			Weight:    t.weight,
+			Queued:    t.jobs.Len(),
+			Running:   t.running,
+			Completed: t.completed,
+		}
+	}
+
+	return s
+}
+
+func (q *fairQueue) tenant(name string) *tenant {
+	t, ok := q.tenants[name]
+	if !ok {
+		weight, ok := q.cfg.Weights[name]
+		if !ok || weight < 1 {
+			weight = q.cfg.DefaultWeight
+		}
+
+		t = &tenant{name: name, weight: weight}
+		q.tenants[name] = t
+	}
+
+	return t
+}
+
+func (q *fairQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
@@ -0,0 +1,138 @@
+package pool_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// journal records the order in which the tasks are executed.
+type journal struct {
+	order []string
+	mu    sync.Mutex
+}
+
+type tenantTask struct {
+	tenant string
+	log    *journal
+}
+
+func (t tenantTask) Tenant() string {
+	return t.tenant
+}
+
+func (t tenantTask) Job(context.Context) {
+	t.log.mu.Lock()
+	t.log.order = append(t.log.order, t.tenant)
+	t.log.mu.Unlock()
+}
+
+func TestPool_WithTenants(t *testing.T) {
+	t.Run("Tenants get their weighted share", func(t *testing.T) {
+		var log journal
+
+		workers := pool.New(1, pool.WithTenants(pool.TenantConfig{
+			Weights: map[string]int{"A": 3, "B": 1},
+		}))
+		workers.Run(context.Background())
+
+		// Occupies the only worker while the tasks are queued.
+		gate := newBlocked()
+		workers.Execute(gate)
+		<-gate.started
+
+		for i := 0; i < 8; i++ {
+			workers.Execute(tenantTask{"A", &log})
+		}
+		for i := 0; i < 8; i++ {
+			workers.Execute(tenantTask{"B", &log})
+		}
+		assert.Equal(t, 16, workers.Stats().Queued)
+
+		close(gate.release)
+		workers.Stop()
+
+		assert.Equal(t, "AAABAAABAABBBBBB", strings.Join(log.order, ""))
+
+		tenants := workers.Tenants()
+		assert.Equal(t, pool.TenantStats{Weight: 3, Completed: 8}, tenants["A"])
+		assert.Equal(t, pool.TenantStats{Weight: 1, Completed: 8}, tenants["B"])
+		assert.Equal(t, uint64(1), tenants[""].Completed)
+	})
+
+	t.Run("Full tenant queue", func(t *testing.T) {
+		var log journal
+
+		workers := pool.New(1, pool.WithTenants(pool.TenantConfig{MaxQueued: 2}))
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		workers.Execute(gate)
+		<-gate.started
+
+		require.NoError(t, workers.Submit(context.Background(), tenantTask{"A", &log}))
+		require.NoError(t, workers.Submit(context.Background(), tenantTask{"A", &log}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := workers.Submit(ctx, tenantTask{"A", &log})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// Other tenants have their own room.
+		require.NoError(t, workers.Submit(context.Background(), tenantTask{"B", &log}))
+
+		close(gate.release)
+		workers.Stop()
+
+		assert.Len(t, log.order, 3)
+		assert.ErrorIs(t, workers.Submit(context.Background(), tenantTask{"A", &log}), pool.ErrPoolStopped)
+	})
+
+	t.Run("Heavy task", func(t *testing.T) {
+		var log journal
+
+		workers := pool.New(1, pool.WithTenants(pool.TenantConfig{
+			Weights: map[string]int{"A": 2},
+		}))
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		workers.Execute(gate)
+		<-gate.started
+
+		workers.Execute(heavyTenantTask{tenantTask{"A", &log}})
+		workers.Execute(tenantTask{"B", &log})
+		workers.Execute(tenantTask{"B", &log})
+
+		close(gate.release)
+		stopped := make(chan struct{})
+		go func() {
+			workers.Stop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("the heavy task is not dispatched")
+		}
+
+		// The other tenant is served while the heavy task waits for its turn.
+		assert.Equal(t, "BBA", strings.Join(log.order, ""))
+	})
+}
+
+// heavyTenantTask costs many scheduler rounds.
+type heavyTenantTask struct {
+	tenantTask
+}
+
+func (heavyTenantTask) Weight() int64 {
+	return 1 << 36
+}
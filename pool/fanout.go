@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrFanOutDone = fmt.Errorf("fan-out has got enough responses")
+)
+
+// FanOutConfig keeps the settings of a fan-out.
+type FanOutConfig struct {
+	// Need is the number of successful responses the fan-out waits for, 0 means all tasks.
+	// The tasks still running when they are collected are cancelled.
+	Need int
+	// Timeout bounds the whole fan-out, 0 means it is bounded by the context only.
+	Timeout time.Duration
+}
+
+// FanOut runs the tasks concurrently in the pool, e.g. the same query to several replicas,
+// and collects their responses in the order of the tasks. It returns when all tasks have finished,
+// Need of them have succeeded, or the timeout has passed. The tasks get a context which is done
+// when the fan-out is over, a task which has not responded by then gets the context error
+// or ErrFanOutDone.
+func (p *NonBlocking[T]) FanOut(ctx context.Context, cfg FanOutConfig, tasks ...NonBlockingRunner[T]) []JobResponse[T] {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(ctx, p.cfg.clock, cfg.Timeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	need := cfg.Need
+	if need < 1 || need > len(tasks) {
+		need = len(tasks)
+	}
+
+	type result struct {
+		i    int
+		resp JobResponse[T]
+	}
+	results := make(chan result, len(tasks))
+	for i, task := range tasks {
+		go func(i int, task NonBlockingRunner[T]) {
+			results <- result{i, p.Submit(ctx, bound[T]{ctx, task})}
+		}(i, task)
+	}
+
+	resps := make([]JobResponse[T], len(tasks))
+	received := make([]bool, len(tasks))
+	succeeded := 0
+	for n := 0; n < len(tasks) && succeeded < need; n++ {
+		r := <-results
+		resps[r.i], received[r.i] = r.resp, true
+		if r.resp.Err == nil {
+			succeeded++
+		}
+	}
+
+	for i := range resps {
+		if !received[i] {
+			resps[i].Err = ErrFanOutDone
+		}
+	}
+
+	return resps
+}
+
+// bound is a task of a non-blocking pool which is cancelled with the context of its caller.
+type bound[T any] struct {
+	ctx  context.Context
+	task NonBlockingRunner[T]
+}
+
+func (b bound[T]) Job(ctx context.Context) JobResponse[T] {
+	ctx, cancel := mergeCancel(ctx, b.ctx)
+	defer cancel()
+
+	resp := b.task.Job(ctx)
+	if errors.Is(resp.Err, context.Canceled) && b.ctx.Err() != nil {
+		// The task has been cancelled by its caller, e.g. when the fan-out timed out.
+		resp.Err = b.ctx.Err()
+	}
+
+	return resp
+}
+
+func (b bound[T]) unwrap() any {
+	return b.task
+}
+
+// mergeCancel returns a copy of ctx which is also done when other is done.
+// The values and the deadline are those of ctx.
+func mergeCancel(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if other.Done() == nil {
+		return ctx, cancel
+	}
+
+	go func() {
+		select {
+		case <-other.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,88 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// replica answers after a delay unless its context is done first.
+type replica struct {
+	name      string
+	delay     time.Duration
+	err       error
+	cancelled *atomic.Int64
+}
+
+func (r replica) Job(ctx context.Context) pool.JobResponse[string] {
+	select {
+	case <-time.After(r.delay):
+		return pool.JobResponse[string]{Value: r.name, Err: r.err}
+	case <-ctx.Done():
+		if r.cancelled != nil {
+			r.cancelled.Add(1)
+		}
+		return pool.JobResponse[string]{Err: ctx.Err()}
+	}
+}
+
+func TestNonBlocking_FanOut(t *testing.T) {
+	workers := pool.NewNonBlocking[string](4)
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	t.Run("All responses", func(t *testing.T) {
+		resps := workers.FanOut(context.Background(), pool.FanOutConfig{},
+			replica{name: "a", delay: 20 * time.Millisecond},
+			replica{name: "b"},
+			replica{name: "c", delay: 10 * time.Millisecond, err: errors.New("down")},
+		)
+
+		require.Len(t, resps, 3)
+		assert.Equal(t, "a", resps[0].Value)
+		assert.Equal(t, "b", resps[1].Value)
+		assert.EqualError(t, resps[2].Err, "down")
+	})
+
+	t.Run("First successful response", func(t *testing.T) {
+		var cancelled atomic.Int64
+
+		resps := workers.FanOut(context.Background(), pool.FanOutConfig{Need: 1},
+			replica{name: "slow", delay: time.Minute, cancelled: &cancelled},
+			replica{name: "failing", err: errors.New("down")},
+			replica{name: "fast", delay: 10 * time.Millisecond},
+		)
+
+		assert.ErrorIs(t, resps[0].Err, pool.ErrFanOutDone)
+		assert.EqualError(t, resps[1].Err, "down")
+		assert.NoError(t, resps[2].Err)
+		assert.Equal(t, "fast", resps[2].Value)
+
+		// The slow replica is cancelled and its worker returns to the pool.
+		require.Eventually(t, func() bool { return cancelled.Load() == 1 }, time.Second, time.Millisecond)
+		require.Eventually(t, func() bool { return workers.Stats().Running == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		var cancelled atomic.Int64
+
+		start := time.Now()
+		resps := workers.FanOut(context.Background(), pool.FanOutConfig{Timeout: 20 * time.Millisecond},
+			replica{name: "a", delay: time.Minute, cancelled: &cancelled},
+			replica{name: "b", delay: time.Minute, cancelled: &cancelled},
+		)
+
+		assert.Less(t, time.Since(start), time.Second)
+		for _, resp := range resps {
+			assert.ErrorIs(t, resp.Err, context.DeadlineExceeded)
+		}
+		require.Eventually(t, func() bool { return cancelled.Load() == 2 }, time.Second, time.Millisecond)
+	})
+}
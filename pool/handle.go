@@ -0,0 +1,290 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaskStatus is the stage of the life of a task.
+type TaskStatus int
+
+const (
+	// TaskQueued is a task waiting for a worker.
+	TaskQueued TaskStatus = iota
+	// TaskRunning is a task being executed by a worker.
+	TaskRunning
+	// TaskDone is a task finished successfully.
+	TaskDone
+	// TaskCancelled is a task cancelled with Handle.Cancel.
+	TaskCancelled
+	// TaskFailed is a task which returned an error or was rejected by the pool.
+	TaskFailed
+)
+
+func (s TaskStatus) String() string {
+	switch s {
+	case TaskQueued:
+		return "queued"
+	case TaskRunning:
+		return "running"
+	case TaskDone:
+		return "done"
+	case TaskCancelled:
+		return "cancelled"
+	case TaskFailed:
+		return "failed"
+	}
+
+	return "unknown"
+}
+
+// Handle controls a task added to a pool. It reports the status of the task and lets
+// the caller cancel it.
+type Handle struct {
+	id       uint64
+	owner    *core
+	task     any
+	w        *worker
+	worker   int
+	status   TaskStatus
+	queued   time.Time
+	started  time.Time
+	finished time.Time
+	value    any
+	err      error
+	// cancel cancels the context of the task, it is nil until the task is bound to one.
+	cancel    context.CancelFunc
+	cancelled bool
+	// stuck is the time the watchdog found the task stuck.
+	stuck time.Time
+	done  chan struct{}
+	// onFinish is called when the task is finished or skipped, it may be nil.
+	onFinish func()
+	mu       sync.Mutex
+}
+
+// ID returns the ID of the task, unique within its pool (see Task).
+func (h *Handle) ID() uint64 {
+	return h.id
+}
+
+// Status returns the current status of the task.
+func (h *Handle) Status() TaskStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.status
+}
+
+// Cancel cancels the task. A queued task is skipped, the context of a running task is cancelled.
+// A task cancelled while running is reported cancelled whatever it returns.
+func (h *Handle) Cancel() {
+	h.mu.Lock()
+	if h.status > TaskRunning || h.cancelled {
+		h.mu.Unlock()
+		return
+	}
+
+	h.cancelled = true
+	if h.cancel != nil {
+		h.cancel()
+	}
+	queued := h.status == TaskQueued
+	h.mu.Unlock()
+
+	if queued {
+		h.finish(nil, context.Canceled)
+	}
+}
+
+// Done returns a channel which is closed when the task is finished, cancelled or rejected.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait waits until the task is finished and returns its error.
+// If ctx is done first, it returns the context error.
+func (h *Handle) Wait(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return h.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Worker returns the ID of the worker which has started the task, or 0 if it has not started.
+func (h *Handle) Worker() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.worker
+}
+
+// Queued returns the time the task was added to the pool.
+func (h *Handle) Queued() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.queued
+}
+
+// Started returns the time a worker started the task, or zero if it has not started.
+func (h *Handle) Started() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.started
+}
+
+// Finished returns the time the task was finished, or zero if it has not finished.
+func (h *Handle) Finished() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.finished
+}
+
+// Info describes the task as Snapshot does.
+func (h *Handle) Info() TaskInfo {
+	return h.info(h.owner.cfg.clock.Now())
+}
+
+// Value returns the value of the finished task. It is a JobResponse value for the tasks
+// of a non-blocking pool and nil for the others.
+func (h *Handle) Value() any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.value
+}
+
+// Err returns the error of the finished task.
+func (h *Handle) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.err
+}
+
+// bind sets the function cancelling the context of the task.
+// It returns false if the task has been cancelled already.
+func (h *Handle) bind(cancel context.CancelFunc) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.status != TaskQueued {
+		return false
+	}
+
+	h.cancel = cancel
+	return true
+}
+
+// start marks the task running in the worker of ctx, cancel replaces the function cancelling
+// its context unless it is nil. It returns false if the task has been cancelled already.
+func (h *Handle) start(ctx context.Context, cancel context.CancelFunc) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.status != TaskQueued {
+		return false
+	}
+
+	h.status = TaskRunning
+	h.started = h.owner.cfg.clock.Now()
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		h.w, h.worker = w, w.id
+	}
+	if cancel != nil {
+		h.cancel = cancel
+	}
+	h.owner.record(h, EventStart, h.started)
+	return true
+}
+
+// skip records the error of a task which has not been started by a worker.
+func (h *Handle) skip(err error) {
+	if h.Status() == TaskQueued {
+		h.finish(nil, err)
+	}
+}
+
+// finish records the result of the task, only the first result counts.
+func (h *Handle) finish(value any, err error) {
+	h.mu.Lock()
+	if h.status > TaskRunning {
+		h.mu.Unlock()
+		return
+	}
+
+	switch {
+	case h.cancelled:
+		h.status = TaskCancelled
+	case err != nil:
+		h.status = TaskFailed
+	default:
+		h.status = TaskDone
+	}
+	h.finished = h.owner.cfg.clock.Now()
+	h.value, h.err = value, err
+	h.owner.record(h, EventFinish, h.finished)
+	h.mu.Unlock()
+
+	// A finished task is forgotten before its waiters wake up, Task does not find it any more.
+	h.owner.untrack(h)
+	close(h.done)
+	if h.onFinish != nil {
+		h.onFinish()
+	}
+}
+
+// Task returns the handle of a queued or running task by its ID.
+func (c *core) Task(id uint64) (*Handle, bool) {
+	h, ok := c.handles.Load(id)
+	if !ok {
+		return nil, false
+	}
+
+	return h.(*Handle), true
+}
+
+// Tasks returns the handles of all queued and running tasks in the order they were added.
+func (c *core) Tasks() []*Handle {
+	tasks := make([]*Handle, 0, c.tracked.Load())
+	c.handles.Range(func(_, h any) bool {
+		tasks = append(tasks, h.(*Handle))
+		return true
+	})
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].id < tasks[j].id })
+	return tasks
+}
+
+// track creates the handle of a task added to the pool.
+func (c *core) track(task any, onFinish func()) *Handle {
+	h := &Handle{
+		id:       c.handleSeq.Add(1),
+		owner:    c,
+		task:     task,
+		queued:   c.cfg.clock.Now(),
+		done:     make(chan struct{}),
+		onFinish: onFinish,
+	}
+	c.record(h, EventEnqueue, h.queued)
+
+	// The tasks are tracked without a lock shared by the callers adding tasks.
+	c.tracked.Add(1)
+	c.handles.Store(h.id, h)
+
+	return h
+}
+
+// untrack forgets a finished task.
+func (c *core) untrack(h *Handle) {
+	if _, ok := c.handles.LoadAndDelete(h.id); ok {
+		c.tracked.Add(-1)
+	}
+}
@@ -0,0 +1,226 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+// cancellable runs until its context is done and reports the context error.
+type cancellable chan struct{}
+
+func (c cancellable) Job(ctx context.Context) error {
+	close(c)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestPool_Handle(t *testing.T) {
+	t.Run("Status and timestamps", func(t *testing.T) {
+		workers := pool.New(1)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		gate := newBlocked()
+		h := workers.Execute(gate)
+		<-gate.started
+
+		assert.Equal(t, pool.TaskRunning, h.Status())
+		assert.False(t, h.Queued().IsZero())
+		assert.False(t, h.Started().Before(h.Queued()))
+		assert.True(t, h.Finished().IsZero())
+
+		close(gate.release)
+		require.NoError(t, h.Wait(context.Background()))
+		assert.Equal(t, pool.TaskDone, h.Status())
+		assert.False(t, h.Finished().Before(h.Started()))
+	})
+
+	t.Run("Failed task", func(t *testing.T) {
+		workers := pool.New(1)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		h := workers.Execute(pool.Fallible(broken{errHostDown}))
+		assert.ErrorIs(t, h.Wait(context.Background()), errHostDown)
+		assert.Equal(t, pool.TaskFailed, h.Status())
+	})
+
+	t.Run("Cancel a running task", func(t *testing.T) {
+		workers := pool.New(1)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		started := make(cancellable)
+		h := workers.Execute(pool.Fallible(started))
+		<-started
+
+		found, ok := workers.Task(h.ID())
+		require.True(t, ok)
+		found.Cancel()
+
+		assert.ErrorIs(t, h.Wait(context.Background()), context.Canceled)
+		assert.Equal(t, pool.TaskCancelled, h.Status())
+
+		_, ok = workers.Task(h.ID())
+		assert.False(t, ok)
+	})
+
+	t.Run("Cancel a queued task", func(t *testing.T) {
+		workers := pool.New(1, pool.WithTenants(pool.TenantConfig{}))
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		running := workers.Execute(gate)
+		<-gate.started
+
+		var cnt atomic.Int64
+		queued := workers.Execute(counted{&cnt})
+		assert.Equal(t, pool.TaskQueued, queued.Status())
+		assert.Equal(t, []*pool.Handle{running, queued}, workers.Tasks())
+
+		queued.Cancel()
+		assert.Equal(t, pool.TaskCancelled, queued.Status())
+		assert.ErrorIs(t, queued.Err(), context.Canceled)
+
+		close(gate.release)
+		workers.Stop()
+
+		assert.Equal(t, int64(0), cnt.Load())
+		assert.Empty(t, workers.Tasks())
+	})
+
+	t.Run("Task not added", func(t *testing.T) {
+		workers := pool.New(1, pool.WithTenants(pool.TenantConfig{}))
+		workers.Run(context.Background())
+		workers.Stop()
+
+		h, err := workers.Start(context.Background(), counted{new(atomic.Int64)})
+		assert.ErrorIs(t, err, pool.ErrPoolStopped)
+		assert.Equal(t, pool.TaskFailed, h.Status())
+		assert.ErrorIs(t, h.Err(), pool.ErrPoolStopped)
+	})
+
+	t.Run("Concurrent tasks", func(t *testing.T) {
+		workers := pool.New(4)
+		workers.Run(context.Background())
+
+		var cnt atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					workers.Execute(counted{&cnt})
+					workers.Tasks()
+				}
+			}()
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, int64(800), cnt.Load())
+		assert.Empty(t, workers.Tasks())
+		require.NoError(t, workers.Drain(context.Background()))
+	})
+
+	t.Run("Info", func(t *testing.T) {
+		clock := pooltest.NewClock(time.Now())
+		workers := pool.New(1, pool.WithClock(clock))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		gate := newBlocked()
+		h := workers.Execute(gate)
+		<-gate.started
+		clock.Advance(time.Second)
+
+		info := h.Info()
+		assert.Equal(t, h.ID(), info.ID)
+		assert.Equal(t, pool.TaskRunning, info.Status)
+		assert.Equal(t, time.Second, info.Elapsed)
+
+		// A finished task does not age.
+		close(gate.release)
+		require.NoError(t, h.Wait(context.Background()))
+		clock.Advance(time.Second)
+		assert.Equal(t, pool.TaskDone, h.Info().Status)
+		assert.Equal(t, time.Second, h.Info().Elapsed)
+	})
+}
+
+// broken fails with an error.
+type broken struct {
+	err error
+}
+
+func (b broken) Job(context.Context) error {
+	return b.err
+}
+
+// counted counts its runs.
+type counted struct {
+	cnt *atomic.Int64
+}
+
+func (c counted) Job(context.Context) {
+	c.cnt.Add(1)
+}
+
+func TestNonBlocking_Handle(t *testing.T) {
+	workers := pool.NewNonBlocking[string](1)
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	t.Run("Value", func(t *testing.T) {
+		h := workers.Start(context.Background(), replica{name: "a"})
+		require.NoError(t, h.Wait(context.Background()))
+
+		assert.Equal(t, pool.TaskDone, h.Status())
+		assert.Equal(t, pool.JobResponse[string]{Value: "a"}, h.Value())
+	})
+
+	t.Run("Failed task", func(t *testing.T) {
+		h := workers.Start(context.Background(), replica{name: "a", err: errors.New("down")})
+
+		assert.EqualError(t, h.Wait(context.Background()), "down")
+		assert.Equal(t, pool.TaskFailed, h.Status())
+	})
+
+	t.Run("Cancel a running task", func(t *testing.T) {
+		var cancelled atomic.Int64
+
+		h := workers.Start(context.Background(), replica{name: "a", delay: time.Minute, cancelled: &cancelled})
+		require.Eventually(t, func() bool { return h.Status() == pool.TaskRunning }, time.Second, time.Millisecond)
+
+		h.Cancel()
+		assert.ErrorIs(t, h.Wait(context.Background()), context.Canceled)
+		assert.Equal(t, pool.TaskCancelled, h.Status())
+		assert.Equal(t, int64(1), cancelled.Load())
+	})
+
+	t.Run("Cancel a task waiting for a worker", func(t *testing.T) {
+		done := make(chan pool.JobResponse[string])
+		go func() {
+			done <- workers.Submit(context.Background(), replica{name: "busy", delay: 50 * time.Millisecond})
+		}()
+		require.Eventually(t, func() bool { return len(workers.Tasks()) == 1 }, time.Second, time.Millisecond)
+
+		h := workers.Start(context.Background(), replica{name: "a"})
+		h.Cancel()
+
+		assert.ErrorIs(t, h.Wait(context.Background()), context.Canceled)
+		assert.Equal(t, pool.TaskCancelled, h.Status())
+		assert.Equal(t, "busy", (<-done).Value)
+	})
+}
@@ -0,0 +1,134 @@
+package pool
+
+import (
+	"time"
+)
+
+// HealthStatus is the state of a pool reported by Health.
+type HealthStatus int
+
+const (
+	// HealthStopped is a pool which has not been started or has been stopped.
+	HealthStopped HealthStatus = iota
+	// HealthRunning is a running pool which takes new tasks.
+	HealthRunning
+	// HealthPaused is a paused pool (see Pause).
+	HealthPaused
+	// HealthDraining is a pool which does not accept new tasks (see Drain).
+	HealthDraining
+	// HealthOverloaded is a running pool with a load above the thresholds of HealthConfig.
+	HealthOverloaded
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthStopped:
+		return "stopped"
+	case HealthRunning:
+		return "running"
+	case HealthPaused:
+		return "paused"
+	case HealthDraining:
+		return "draining"
+	case HealthOverloaded:
+		return "overloaded"
+	default:
+		return "unknown"
+	}
+}
+
+// HealthConfig sets the thresholds of an overloaded pool, a zero threshold is not checked.
+type HealthConfig struct {
+	// MaxQueued is the number of tasks waiting for a worker the pool is overloaded above.
+	MaxQueued int
+	// MaxWait is the time the longest waiting task may wait for a worker.
+	MaxWait time.Duration
+	// MaxBusy is the time every worker may be busy without a break.
+	MaxBusy time.Duration
+}
+
+// Health is the state of a pool at a moment.
+type Health struct {
+	Status HealthStatus
+	// Queued is the number of tasks waiting for a worker.
+	Queued int
+	// Wait is the time the longest waiting task has been waiting for a worker.
+	Wait time.Duration
+	// Busy is the time every worker of the pool has been busy, zero if a worker is free.
+	Busy time.Duration
+}
+
+// Live reports whether the pool is running, even if it does not take new tasks now.
+func (h Health) Live() bool {
+	return h.Status != HealthStopped
+}
+
+// Ready reports whether the pool takes new tasks without delay.
+func (h Health) Ready() bool {
+	return h.Status == HealthRunning
+}
+
+// Lifecycle phases of a pool.
+const (
+	phaseNew int32 = iota
+	phaseRunning
+	phaseStopped
+)
+
+// Health returns the state of the pool. It is overloaded by the thresholds of WithHealth.
+func (c *core) Health() Health {
+	now := c.cfg.clock.Now()
+	h := Health{Status: HealthRunning}
+	for _, t := range c.Tasks() {
+		info := t.info(now)
+		if info.Status != TaskQueued {
+			continue
+		}
+
+		h.Queued++
+		if info.Elapsed > h.Wait {
+			h.Wait = info.Elapsed
+		}
+	}
+	if since := c.saturated.Load(); since != 0 {
+		h.Busy = now.Sub(time.Unix(0, since))
+	}
+
+	switch {
+	case c.phase.Load() != phaseRunning:
+		h.Status = HealthStopped
+	case c.Draining():
+		h.Status = HealthDraining
+	case c.Paused():
+		h.Status = HealthPaused
+	case c.overloaded(h):
+		h.Status = HealthOverloaded
+	}
+
+	return h
+}
+
+// overloaded reports whether the load of the pool is above the thresholds of WithHealth.
+func (c *core) overloaded(h Health) bool {
+	cfg := c.cfg.health
+	if cfg == nil {
+		return false
+	}
+
+	return cfg.MaxQueued > 0 && h.Queued > cfg.MaxQueued ||
+		cfg.MaxWait > 0 && h.Wait > cfg.MaxWait ||
+		cfg.MaxBusy > 0 && h.Busy > cfg.MaxBusy
+}
+
+// occupy counts a task in and marks the time the last free worker took a task.
+func (c *core) occupy() {
+	if c.running.Add(1) >= int64(c.limit()) {
+		c.saturated.CompareAndSwap(0, c.cfg.clock.Now().UnixNano())
+	}
+}
+
+// rest marks a worker waiting for a task with no task or caller waiting for it,
+// so the pool is not saturated any more.
+func (c *core) rest() {
+	c.saturated.Store(0)
+}
@@ -0,0 +1,156 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("Lifecycle", func(t *testing.T) {
+		workers := pool.New(2)
+		assert.Equal(t, pool.HealthStopped, workers.Health().Status)
+		assert.False(t, workers.Health().Live())
+
+		workers.Run(context.Background())
+		h := workers.Health()
+		assert.Equal(t, pool.HealthRunning, h.Status)
+		assert.True(t, h.Live())
+		assert.True(t, h.Ready())
+		assert.Zero(t, h.Busy)
+
+		workers.Pause()
+		assert.Equal(t, pool.HealthPaused, workers.Health().Status)
+		assert.True(t, workers.Health().Live())
+		assert.False(t, workers.Health().Ready())
+		workers.Resume()
+
+		require.NoError(t, workers.Drain(context.Background()))
+		assert.Equal(t, pool.HealthDraining, workers.Health().Status)
+		workers.Resume()
+		assert.Equal(t, pool.HealthRunning, workers.Health().Status)
+
+		workers.Stop()
+		assert.Equal(t, pool.HealthStopped, workers.Health().Status)
+	})
+
+	t.Run("Every worker busy", func(t *testing.T) {
+		workers := pool.New(2, pool.WithHealth(pool.HealthConfig{MaxBusy: 20 * time.Millisecond}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		first, second := newBlocked(), newBlocked()
+		workers.Execute(first)
+		<-first.started
+		// A free worker keeps the pool healthy.
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, pool.HealthRunning, workers.Health().Status)
+		assert.Zero(t, workers.Health().Busy)
+
+		workers.Execute(second)
+		<-second.started
+		require.Eventually(t, func() bool {
+			return workers.Health().Status == pool.HealthOverloaded
+		}, time.Second, time.Millisecond)
+		assert.Greater(t, workers.Health().Busy, 20*time.Millisecond)
+
+		close(first.release)
+		require.Eventually(t, func() bool {
+			return workers.Health().Status == pool.HealthRunning
+		}, time.Second, time.Millisecond)
+		assert.Zero(t, workers.Health().Busy)
+		close(second.release)
+	})
+
+	t.Run("Continuous load", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](2, pool.WithHealth(pool.HealthConfig{MaxBusy: 30 * time.Millisecond}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		// The callers keep every worker busy with short tasks.
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+
+					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+					workers.Submit(ctx, replica{name: "a", delay: 5 * time.Millisecond})
+					cancel()
+				}
+			}()
+		}
+
+		require.Eventually(t, func() bool {
+			return workers.Health().Status == pool.HealthOverloaded
+		}, time.Second, time.Millisecond)
+		assert.Greater(t, workers.Health().Busy, 30*time.Millisecond)
+
+		close(stop)
+		wg.Wait()
+		require.Eventually(t, func() bool {
+			return workers.Health().Status == pool.HealthRunning
+		}, time.Second, time.Millisecond)
+		assert.Zero(t, workers.Health().Busy)
+	})
+
+	t.Run("Queued tasks", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1, pool.WithHealth(pool.HealthConfig{MaxQueued: 1}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		running := workers.Start(context.Background(), replica{name: "a", delay: time.Minute})
+		require.Eventually(t, func() bool { return running.Status() == pool.TaskRunning }, time.Second, time.Millisecond)
+
+		workers.Start(context.Background(), replica{name: "b"})
+		require.Eventually(t, func() bool { return workers.Health().Queued == 1 }, time.Second, time.Millisecond)
+		assert.Equal(t, pool.HealthRunning, workers.Health().Status)
+
+		workers.Start(context.Background(), replica{name: "c"})
+		require.Eventually(t, func() bool { return workers.Health().Queued == 2 }, time.Second, time.Millisecond)
+		h := workers.Health()
+		assert.Equal(t, pool.HealthOverloaded, h.Status)
+		assert.Greater(t, h.Wait, time.Duration(0))
+
+		running.Cancel()
+		require.Eventually(t, func() bool { return workers.Health().Queued == 0 }, time.Second, time.Millisecond)
+		assert.Equal(t, pool.HealthRunning, workers.Health().Status)
+	})
+
+	t.Run("Wait time", func(t *testing.T) {
+		workers := pool.New(1, pool.WithHealth(pool.HealthConfig{MaxWait: 20 * time.Millisecond}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		gate := newBlocked()
+		workers.Execute(gate)
+		<-gate.started
+		queued := make(chan *pool.Handle)
+		go func() { queued <- workers.Execute(signal(make(chan struct{}))) }()
+
+		require.Eventually(t, func() bool {
+			return workers.Health().Status == pool.HealthOverloaded
+		}, time.Second, time.Millisecond)
+		assert.Greater(t, workers.Health().Wait, 20*time.Millisecond)
+		close(gate.release)
+		require.NoError(t, (<-queued).Wait(context.Background()))
+	})
+}
+
+func TestHealthStatus_String(t *testing.T) {
+	assert.Equal(t, "overloaded", pool.HealthOverloaded.String())
+	assert.Equal(t, "unknown", pool.HealthStatus(-1).String())
+}
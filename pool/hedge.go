@@ -0,0 +1,191 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeConfig keeps the settings of hedged requests.
+type HedgeConfig struct {
+	// Percentile of the observed latencies after which a duplicate of a task is submitted.
+	// Default is 0.95.
+	Percentile float64
+	// Delay is the hedging delay used until MinSamples latencies are observed. Default is 100ms.
+	Delay time.Duration
+	// MinSamples is the number of latencies needed to use the percentile. Default is 20.
+	MinSamples int
+	// Window is the number of the latest latencies the percentile is taken from. Default is 1000.
+	Window int
+	// MaxHedges is the number of duplicates submitted for a task. Default is 1.
+	MaxHedges int
+}
+
+// HedgeStats keeps statistic values about hedged requests.
+type HedgeStats struct {
+	// Delay is the current hedging delay.
+	Delay time.Duration
+	// Requests is the number of submitted tasks.
+	Requests uint64
+	// Hedged is the number of the tasks for which a duplicate was submitted.
+	Hedged uint64
+	// Won is the number of the tasks answered by a duplicate.
+	Won uint64
+}
+
+// Hedger submits tasks to a non-blocking pool and, when the response is late, a duplicate
+// of the task to another worker. The first successful response wins and the other
+// attempts are cancelled through their context. The delay follows a percentile of
+// the latencies, so only the slowest tasks are duplicated.
+// The tasks have to be idempotent.
+type Hedger[T any] struct {
+	cfg     HedgeConfig
+	pool    *NonBlocking[T]
+	samples []time.Duration
+	next    int
+	added   int
+	delay   time.Duration
+	stats   HedgeStats
+	mu      sync.Mutex
+}
+
+// hedgeRecomputeEvery is the number of new latencies after which the delay is computed again.
+const hedgeRecomputeEvery = 10
+
+// NewHedger creates a new Hedger submitting tasks to the pool.
+func NewHedger[T any](p *NonBlocking[T], cfg HedgeConfig) *Hedger[T] {
+	if cfg.Percentile <= 0 || cfg.Percentile > 1 {
+		cfg.Percentile = 0.95
+	}
+	if cfg.Delay <= 0 {
+		cfg.Delay = 100 * time.Millisecond
+	}
+	if cfg.MinSamples < 1 {
+		cfg.MinSamples = 20
+	}
+	if cfg.Window < cfg.MinSamples {
+		cfg.Window = 1000
+	}
+	if cfg.MaxHedges < 1 {
+		cfg.MaxHedges = 1
+	}
+
+	return &Hedger[T]{
+		cfg:   cfg,
+		pool:  p,
+		delay: cfg.Delay,
+	}
+}
+
+// Submit executes a task in a free worker and waits for the response, submitting duplicates
+// of the task while the response is late. If all attempts fail, it returns the last error.
+func (h *Hedger[T]) Submit(ctx context.Context, task NonBlockingRunner[T]) JobResponse[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		attempt int
+		resp    JobResponse[T]
+		latency time.Duration
+	}
+	results := make(chan result, h.cfg.MaxHedges+1)
+	launch := func(attempt int) {
+		go func() {
+			start := h.pool.cfg.clock.Now()
+			resp := h.pool.Submit(ctx, bound[T]{ctx, task})
+			results <- result{attempt, resp, h.pool.cfg.clock.Now().Sub(start)}
+		}()
+	}
+
+	h.mu.Lock()
+	h.stats.Requests++
+	delay := h.delay
+	h.mu.Unlock()
+
+	launch(0)
+	launched, running := 1, 1
+	timer := h.pool.cfg.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	var last JobResponse[T]
+	for {
+		select {
+		case r := <-results:
+			running--
+			if r.resp.Err == nil {
+				h.observe(r.latency, r.attempt > 0)
+				return r.resp
+			}
+
+			last = r.resp
+			if running > 0 {
+				continue
+			}
+			if launched > h.cfg.MaxHedges || ctx.Err() != nil {
+				return last
+			}
+
+		case <-timer.C():
+			if launched > h.cfg.MaxHedges {
+				continue
+			}
+
+		case <-ctx.Done():
+			return JobResponse[T]{Err: ctx.Err()}
+		}
+
+		// The response is late or all attempts have failed, one more goes to another worker.
+		if launched == 1 {
+			h.mu.Lock()
+			h.stats.Hedged++
+			h.mu.Unlock()
+		}
+		launch(launched)
+		launched++
+		running++
+		timer.Reset(delay)
+	}
+}
+
+// Stats returns the current statistic values of the hedged requests.
+func (h *Hedger[T]) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stats
+	s.Delay = h.delay
+
+	return s
+}
+
+// observe records the latency of a successful attempt.
+func (h *Hedger[T]) observe(latency time.Duration, hedge bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if hedge {
+		h.stats.Won++
+	}
+
+	if len(h.samples) < h.cfg.Window {
+		h.samples = append(h.samples, latency)
+	} else {
+		h.samples[h.next] = latency
+		h.next = (h.next + 1) % h.cfg.Window
+	}
+
+	// The delay is computed once there are enough latencies, and then again every few of them.
+	h.added++
+	if len(h.samples) < h.cfg.MinSamples {
+		return
+	}
+	if len(h.samples) > h.cfg.MinSamples && h.added < hedgeRecomputeEvery {
+		return
+	}
+	h.added = 0
+
+	sorted := append([]time.Duration(nil), h.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	h.delay = sorted[int(h.cfg.Percentile*float64(len(sorted)-1))]
+}
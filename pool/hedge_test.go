@@ -0,0 +1,94 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// straggler stalls on its first attempt and answers at once on the others.
+type straggler struct {
+	attempts  *atomic.Int64
+	cancelled *atomic.Int64
+}
+
+func (s straggler) Job(ctx context.Context) pool.JobResponse[string] {
+	if s.attempts.Add(1) > 1 {
+		return pool.JobResponse[string]{Value: "hedge"}
+	}
+
+	return replica{name: "primary", delay: time.Minute, cancelled: s.cancelled}.Job(ctx)
+}
+
+func TestHedger_Submit(t *testing.T) {
+	workers := pool.NewNonBlocking[string](4)
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	t.Run("Fast response is not hedged", func(t *testing.T) {
+		h := pool.NewHedger(workers, pool.HedgeConfig{Delay: time.Minute})
+
+		resp := h.Submit(context.Background(), replica{name: "a"})
+		require.NoError(t, resp.Err)
+		assert.Equal(t, "a", resp.Value)
+
+		s := h.Stats()
+		assert.Equal(t, uint64(1), s.Requests)
+		assert.Equal(t, uint64(0), s.Hedged)
+	})
+
+	t.Run("Late response is hedged and the loser is cancelled", func(t *testing.T) {
+		var attempts, cancelled atomic.Int64
+		h := pool.NewHedger(workers, pool.HedgeConfig{Delay: 10 * time.Millisecond})
+
+		resp := h.Submit(context.Background(), straggler{&attempts, &cancelled})
+		require.NoError(t, resp.Err)
+		assert.Equal(t, "hedge", resp.Value)
+		assert.Equal(t, int64(2), attempts.Load())
+
+		s := h.Stats()
+		assert.Equal(t, uint64(1), s.Hedged)
+		assert.Equal(t, uint64(1), s.Won)
+
+		require.Eventually(t, func() bool { return cancelled.Load() == 1 }, time.Second, time.Millisecond)
+		require.Eventually(t, func() bool { return workers.Stats().Running == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Failed attempt is retried at once", func(t *testing.T) {
+		h := pool.NewHedger(workers, pool.HedgeConfig{Delay: time.Minute, MaxHedges: 2})
+
+		resp := h.Submit(context.Background(), replica{name: "a", err: errors.New("down")})
+		assert.EqualError(t, resp.Err, "down")
+		assert.Equal(t, uint64(1), h.Stats().Hedged)
+	})
+
+	t.Run("Delay follows the percentile of the latencies", func(t *testing.T) {
+		h := pool.NewHedger(workers, pool.HedgeConfig{Delay: time.Minute, MinSamples: 10, Percentile: 0.9})
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, h.Submit(context.Background(), replica{name: "a"}).Err)
+		}
+
+		assert.Less(t, h.Stats().Delay, 100*time.Millisecond)
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		var cancelled atomic.Int64
+		h := pool.NewHedger(workers, pool.HedgeConfig{Delay: 5 * time.Millisecond})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		resp := h.Submit(ctx, replica{name: "a", delay: time.Minute, cancelled: &cancelled})
+		assert.ErrorIs(t, resp.Err, context.DeadlineExceeded)
+
+		require.Eventually(t, func() bool { return cancelled.Load() == 2 }, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,227 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter adjusts the number of tasks a pool executes simultaneously.
+// The pool reports the latency and the error of every finished task to Observe
+// and never runs more than Limit() tasks (or more than its number of workers) at once.
+type Limiter interface {
+	Limit() int
+	Observe(latency time.Duration, err error)
+}
+
+// AIMDConfig keeps the settings of an AIMD limiter.
+type AIMDConfig struct {
+	// Initial is the limit before any task is observed.
+	Initial int
+	// Min and Max bound the limit.
+	Min int
+	Max int
+	// BackoffRatio multiplies the limit when a task fails or is too slow. Default is 0.9.
+	BackoffRatio float64
+	// LatencyThreshold is the latency above which a successful task is treated as a failure.
+	// Zero means only errors decrease the limit.
+	LatencyThreshold time.Duration
+}
+
+// AIMDLimiter increases the limit by one after a window of successful tasks
+// and multiplicatively decreases it after a failed or a too slow one.
+type AIMDLimiter struct {
+	cfg   AIMDConfig
+	limit float64
+	mu    sync.Mutex
+}
+
+// NewAIMDLimiter creates a new additive increase / multiplicative decrease limiter.
+func NewAIMDLimiter(cfg AIMDConfig) *AIMDLimiter {
+	if cfg.Min < 1 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+		cfg.BackoffRatio = 0.9
+	}
+
+	return &AIMDLimiter{
+		cfg:   cfg,
+		limit: clampLimit(float64(cfg.Initial), cfg.Min, cfg.Max),
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (l *AIMDLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// Observe adjusts the limit according to the outcome of a finished task.
+func (l *AIMDLimiter) Observe(latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil || (l.cfg.LatencyThreshold > 0 && latency > l.cfg.LatencyThreshold) {
+		l.limit = clampLimit(l.limit*l.cfg.BackoffRatio, l.cfg.Min, l.cfg.Max)
+		return
+	}
+
+	l.limit = clampLimit(l.limit+1/l.limit, l.cfg.Min, l.cfg.Max)
+}
+
+// GradientConfig keeps the settings of a gradient limiter.
+type GradientConfig struct {
+	// Initial is the limit before any task is observed.
+	Initial int
+	// Min and Max bound the limit.
+	Min int
+	Max int
+	// Smoothing is the weight of a new limit estimate. Default is 0.2.
+	Smoothing float64
+	// ProbeSamples is the number of samples after which the no-load latency is measured again.
+	// Default is 1000.
+	ProbeSamples int
+}
+
+// GradientLimiter compares the current task latency with the lowest observed one
+// and shrinks the limit when tasks start queueing up for a resource, in the manner of TCP Vegas.
+type GradientLimiter struct {
+	cfg     GradientConfig
+	limit   float64
+	minRTT  time.Duration
+	rtt     float64
+	samples int
+	mu      sync.Mutex
+}
+
+// NewGradientLimiter creates a new latency gradient limiter.
+func NewGradientLimiter(cfg GradientConfig) *GradientLimiter {
+	if cfg.Min < 1 {
+		cfg.Min = 1
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = 0.2
+	}
+	if cfg.ProbeSamples < 1 {
+		cfg.ProbeSamples = 1000
+	}
+
+	return &GradientLimiter{
+		cfg:   cfg,
+		limit: clampLimit(float64(cfg.Initial), cfg.Min, cfg.Max),
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (l *GradientLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// Observe adjusts the limit according to the outcome of a finished task.
+func (l *GradientLimiter) Observe(latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil {
+		l.limit = clampLimit(l.limit*0.9, l.cfg.Min, l.cfg.Max)
+		return
+	}
+
+	l.samples++
+	if l.samples >= l.cfg.ProbeSamples {
+		// Forgets the no-load latency, it could have changed since it was measured.
+		l.samples = 0
+		l.minRTT = 0
+	}
+
+	if l.minRTT == 0 || latency < l.minRTT {
+		l.minRTT = latency
+	}
+	if l.rtt == 0 {
+		l.rtt = float64(latency)
+	} else {
+		l.rtt += l.cfg.Smoothing * (float64(latency) - l.rtt)
+	}
+	if l.rtt <= 0 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, float64(l.minRTT)/l.rtt))
+	estimate := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = clampLimit(l.limit+l.cfg.Smoothing*(estimate-l.limit), l.cfg.Min, l.cfg.Max)
+}
+
+func clampLimit(limit float64, min, max int) float64 {
+	return math.Max(float64(min), math.Min(float64(max), limit))
+}
+
+// gate lets no more than the limiter allows workers to take tasks.
+type gate struct {
+	limiter  Limiter
+	inflight int
+	wake     chan struct{}
+	mu       sync.Mutex
+}
+
+func newGate(limiter Limiter) *gate {
+	return &gate{
+		limiter: limiter,
+		wake:    make(chan struct{}),
+	}
+}
+
+// enter blocks until the number of workers inside the gate is below the limit or ctx is done.
+func (g *gate) enter(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if g.inflight < g.limiter.Limit() {
+			g.inflight++
+			g.mu.Unlock()
+			return nil
+		}
+		wake := g.wake
+		g.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// leave lets another worker into the gate.
+func (g *gate) leave() {
+	g.mu.Lock()
+	g.inflight--
+	g.notify()
+	g.mu.Unlock()
+}
+
+// observe reports a finished task to the limiter and wakes the waiting workers up,
+// the limit could have grown.
+func (g *gate) observe(latency time.Duration, err error) {
+	g.limiter.Observe(latency, err)
+
+	g.mu.Lock()
+	g.notify()
+	g.mu.Unlock()
+}
+
+func (g *gate) notify() {
+	close(g.wake)
+	g.wake = make(chan struct{})
+}
@@ -0,0 +1,141 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// fixedLimiter is a limiter with a constant limit which counts observed tasks.
+type fixedLimiter struct {
+	limit    int
+	observed atomic.Int64
+}
+
+func (l *fixedLimiter) Limit() int {
+	return l.limit
+}
+
+func (l *fixedLimiter) Observe(time.Duration, error) {
+	l.observed.Add(1)
+}
+
+func TestAIMDLimiter(t *testing.T) {
+	t.Run("Successes increase the limit up to max", func(t *testing.T) {
+		l := pool.NewAIMDLimiter(pool.AIMDConfig{Initial: 2, Min: 1, Max: 5})
+
+		for i := 0; i < 100; i++ {
+			l.Observe(time.Millisecond, nil)
+		}
+
+		assert.Equal(t, 5, l.Limit())
+	})
+
+	t.Run("Failures decrease the limit down to min", func(t *testing.T) {
+		l := pool.NewAIMDLimiter(pool.AIMDConfig{Initial: 10, Min: 2, Max: 10})
+
+		l.Observe(time.Millisecond, errors.New("failure"))
+		assert.Equal(t, 9, l.Limit())
+
+		for i := 0; i < 100; i++ {
+			l.Observe(time.Millisecond, errors.New("failure"))
+		}
+		assert.Equal(t, 2, l.Limit())
+	})
+
+	t.Run("Slow tasks decrease the limit", func(t *testing.T) {
+		l := pool.NewAIMDLimiter(pool.AIMDConfig{
+			Initial:          10,
+			Max:              10,
+			LatencyThreshold: 10 * time.Millisecond,
+		})
+
+		l.Observe(5*time.Millisecond, nil)
+		assert.Equal(t, 10, l.Limit())
+
+		l.Observe(20*time.Millisecond, nil)
+		assert.Equal(t, 9, l.Limit())
+	})
+}
+
+func TestGradientLimiter(t *testing.T) {
+	t.Run("Steady latency grows the limit", func(t *testing.T) {
+		l := pool.NewGradientLimiter(pool.GradientConfig{Initial: 4, Max: 20})
+
+		for i := 0; i < 100; i++ {
+			l.Observe(10*time.Millisecond, nil)
+		}
+
+		assert.Equal(t, 20, l.Limit())
+	})
+
+	t.Run("Growing latency shrinks the limit", func(t *testing.T) {
+		l := pool.NewGradientLimiter(pool.GradientConfig{Initial: 50, Min: 1, Max: 50})
+
+		l.Observe(10*time.Millisecond, nil)
+		for i := 0; i < 100; i++ {
+			l.Observe(100*time.Millisecond, nil)
+		}
+
+		assert.Less(t, l.Limit(), 50)
+	})
+}
+
+func TestNonBlocking_WithLimiter(t *testing.T) {
+	t.Run("Limit bounds running tasks", func(t *testing.T) {
+		const total = 20
+
+		var used budget
+		var wg sync.WaitGroup
+		limiter := &fixedLimiter{limit: 2}
+
+		workers := pool.NewNonBlocking[int64](8, pool.WithLimiter(limiter))
+		workers.Run(context.Background())
+		requests := workers.RequestChan()
+
+		assert.Equal(t, 2, workers.Stats().Limit)
+
+		for i := 0; i < total; i++ {
+			wg.Add(1)
+			req := <-requests
+			req.Request <- &heavyResponse{heavy{1, &used, nil}}
+
+			go func() {
+				defer wg.Done()
+				<-req.Response
+			}()
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, int64(total), limiter.observed.Load())
+		assert.LessOrEqual(t, used.max, int64(2))
+	})
+}
+
+func TestPool_WithLimiter(t *testing.T) {
+	t.Run("Limit bounds running tasks", func(t *testing.T) {
+		var used budget
+		var wg sync.WaitGroup
+
+		workers := pool.New(8, pool.WithLimiter(&fixedLimiter{limit: 3}))
+		workers.Run(context.Background())
+
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			workers.Execute(&heavy{1, &used, &wg})
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, 30, used.done)
+		assert.LessOrEqual(t, used.max, int64(3))
+	})
+}
@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"time"
+)
+
+var (
+	ErrMemoryBudget = fmt.Errorf("memory budget exceeded")
+)
+
+// heapMetric is the runtime metric sampled to find out the heap usage.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// heapPollInterval is how often a waiting task re-checks the heap usage.
+const heapPollInterval = 10 * time.Millisecond
+
+// AdmissionMode defines what the pool does with a task which does not fit into a budget.
+type AdmissionMode int
+
+const (
+	// AdmissionWait delays the task until the budget allows it to run.
+	AdmissionWait AdmissionMode = iota
+	// AdmissionReject skips the task with ErrMemoryBudget.
+	AdmissionReject
+)
+
+// MemoryEstimator is an optional interface for a task which declares how many bytes it needs.
+type MemoryEstimator interface {
+	MemoryEstimate() int64
+}
+
+// memoryOf returns the memory estimate of a task limited by the size of the budget.
+func memoryOf(task any, size int64) int64 {
+	t, ok := taskAs[MemoryEstimator](task)
+	if !ok {
+		return 0
+	}
+
+	switch m := t.MemoryEstimate(); {
+	case m < 0:
+		return 0
+	case m > size:
+		return size
+	default:
+		return m
+	}
+}
+
+// admitMemory reserves the declared memory of a task and checks the sampled heap usage.
+// The returned function gives the reserved memory back.
+func (c *core) admitMemory(ctx context.Context, task any) (func(), error) {
+	release := func() {}
+
+	if c.memory != nil {
+		m := memoryOf(task, c.cfg.memoryBudget)
+
+		switch {
+		case c.cfg.memoryMode == AdmissionReject:
+			if !c.memory.tryAcquire(m) {
+				return nil, ErrMemoryBudget
+			}
+
+		default:
+			if err := c.memory.acquire(ctx, m); err != nil {
+				return nil, err
+			}
+		}
+
+		release = func() { c.memory.release(m) }
+	}
+
+	if c.cfg.heapLimit > 0 {
+		if err := c.waitHeap(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// waitHeap returns when the heap usage is below the limit.
+// In the reject mode it returns ErrMemoryBudget instead of waiting.
+func (c *core) waitHeap(ctx context.Context) error {
+	for heapUsage() > c.cfg.heapLimit {
+		if c.cfg.heapMode == AdmissionReject {
+			return ErrMemoryBudget
+		}
+
+		timer := c.cfg.clock.NewTimer(heapPollInterval)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// heapUsage returns the number of bytes occupied by heap objects.
+func heapUsage() uint64 {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,133 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+type allocation struct {
+	bytes   int64
+	release chan struct{}
+}
+
+func (a *allocation) MemoryEstimate() int64 {
+	return a.bytes
+}
+
+func (a *allocation) Job(context.Context) pool.JobResponse[int64] {
+	if a.release != nil {
+		<-a.release
+	}
+
+	return pool.JobResponse[int64]{Value: a.bytes}
+}
+
+type estimated struct {
+	heavy
+}
+
+func (e *estimated) MemoryEstimate() int64 {
+	return e.weight
+}
+
+func TestNonBlocking_WithMemoryBudget(t *testing.T) {
+	t.Run("Task over budget is rejected", func(t *testing.T) {
+		workers := pool.NewNonBlocking[int64](2, pool.WithMemoryBudget(100, pool.AdmissionReject))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		requests := workers.RequestChan()
+		first := &allocation{bytes: 80, release: make(chan struct{})}
+		req1 := <-requests
+		req1.Request <- first
+
+		// Waits until the first task occupies its part of the budget.
+		require.Eventually(t, func() bool {
+			req2 := <-requests
+			req2.Request <- &allocation{bytes: 50}
+			resp := <-req2.Response
+
+			return resp.Err != nil
+		}, time.Second, time.Millisecond)
+
+		close(first.release)
+		resp := <-req1.Response
+		require.NoError(t, resp.Err)
+
+		req3 := <-requests
+		req3.Request <- &allocation{bytes: 50}
+		resp = <-req3.Response
+		assert.NoError(t, resp.Err)
+		assert.Equal(t, int64(50), resp.Value)
+	})
+
+	t.Run("Rejected task error", func(t *testing.T) {
+		workers := pool.NewNonBlocking[int64](1, pool.WithHeapLimit(1, pool.AdmissionReject))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		req := <-workers.RequestChan()
+		req.Request <- &allocation{bytes: 1}
+		resp := <-req.Response
+
+		assert.ErrorIs(t, resp.Err, pool.ErrMemoryBudget)
+	})
+}
+
+func TestPool_WithMemoryBudget(t *testing.T) {
+	t.Run("Tasks wait for the budget", func(t *testing.T) {
+		var used budget
+		var wg sync.WaitGroup
+
+		workers := pool.New(4, pool.WithMemoryBudget(100, pool.AdmissionWait))
+		workers.Run(context.Background())
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			workers.Execute(&estimated{heavy{60, &used, &wg}})
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, 20, used.done)
+		assert.Equal(t, int64(60), used.max)
+	})
+
+	t.Run("Rejected tasks are reported", func(t *testing.T) {
+		var used budget
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var rejected []error
+
+		workers := pool.New(2,
+			pool.WithHeapLimit(1, pool.AdmissionReject),
+			pool.WithRejectHandler(func(task any, err error) {
+				mu.Lock()
+				rejected = append(rejected, err)
+				mu.Unlock()
+				task.(*heavy).wg.Done()
+			}),
+		)
+		workers.Run(context.Background())
+
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			workers.Execute(&heavy{1, &used, &wg})
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, 0, used.done)
+		require.Len(t, rejected, 5)
+		for _, err := range rejected {
+			assert.ErrorIs(t, err, pool.ErrMemoryBudget)
+		}
+	})
+}
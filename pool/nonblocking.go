@@ -2,7 +2,9 @@ package pool
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sync"
 )
 
@@ -12,11 +14,11 @@ var (
 
 // NonBlocking carries a worker tasks channel, a wait group, and other values.
 type NonBlocking[T any] struct {
-	cancel     context.CancelFunc
-	requests   chan *JobRequest[T]
-	start      sync.WaitGroup
-	finish     sync.WaitGroup
-	workersCnt int
+	*core
+	ctx      context.Context
+	cancel   context.CancelFunc
+	requests chan *JobRequest[T]
+	finish   sync.WaitGroup
 }
 
 // JobResponse keeps a response from a task sent to a worker.
@@ -42,49 +44,420 @@ type NonBlockingRunner[T any] interface {
 }
 
 // NewNonBlocking creates a new worker pool.
-func NewNonBlocking[T any](workersCnt int) *NonBlocking[T] {
+// It panics if the options include WithTenants, the non-blocking pool does not queue tasks per tenant.
+func NewNonBlocking[T any](workersCnt int, opts ...Option) *NonBlocking[T] {
+	// The options are checked before newCore publishes the pool.
+	if newConfig(opts).tenants != nil {
+		panic("pool: WithTenants is not supported by the non-blocking pool")
+	}
+
 	return &NonBlocking[T]{
-		requests:   make(chan *JobRequest[T]),
-		workersCnt: workersCnt,
+		core:     newCore(workersCnt, opts),
+		requests: make(chan *JobRequest[T]),
 	}
 }
 
 // Run starts workers in the pool.
+// With WithIdleTimeout, the workers are spawned later on demand.
 func (p *NonBlocking[T]) Run(ctx context.Context) {
 	ctx, p.cancel = context.WithCancel(ctx)
+	p.ctx = ctx
+	p.phase.Store(phaseRunning)
+
+	p.fill()
+
+	if p.queue != nil {
+		p.finish.Add(1)
+		go p.dispatch(ctx)
+	}
+	p.startWatchdog(p.replace)
+}
+
+// fill spawns the missing workers of a pool which is not lazy.
+func (p *NonBlocking[T]) fill() {
+	if p.lazy() {
+		return
+	}
+	for p.reserve(math.MaxInt64) {
+		p.spawn(p.ctx)
+	}
+}
+
+// Resize changes the number of workers of the pool. The extra workers exit when they finish
+// their current tasks.
+func (p *NonBlocking[T]) Resize(n int) error {
+	if err := p.resize(n); err != nil {
+		return err
+	}
+
+	if p.ctx != nil {
+		p.fill()
+		p.grow()
+	}
+	return nil
+}
+
+// spawn starts a worker which offers itself to the callers until ctx is done or the worker is retired.
+// The worker is set up before spawn returns, so a retired worker is torn down only after
+// its successor is ready and the pool does not shrink.
+func (p *NonBlocking[T]) spawn(ctx context.Context) {
+	wctx, w := p.newWorker(ctx)
+	p.finish.Add(1)
 
-	p.start.Add(p.workersCnt)
-	p.finish.Add(p.workersCnt)
-	for i := 0; i < p.workersCnt; i++ {
-		go func() {
-			defer p.finish.Done()
-			p.start.Done()
+	go func() {
+		defer func() {
+			// The watchdog has already let the pool go without an abandoned worker.
+			if !w.abandoned() {
+				p.finish.Done()
+			}
+		}()
+		defer p.stopWorker(wctx, w)
+		p.pin()
+		p.mark(w)
 
-			for {
-				req := NewJobRequest[T]()
+		for {
+			w.wakeup = p.nudged()
+			if p.dismiss() {
+				// The pool has been resized down.
+				return
+			}
+			if err := p.awaitResume(ctx); err != nil {
+				p.live.Add(-1)
+				return
+			}
+			if err := p.enter(ctx); err != nil {
+				p.live.Add(-1)
+				return
+			}
+			req := NewJobRequest[T]()
+			p.idle.Add(1)
+			taken, open := p.offer(ctx, w, req)
+			p.idle.Add(-1)
 
-				select {
-				case <-ctx.Done():
+			if !taken {
+				p.leave()
+				if w.nudged {
+					w.nudged = false
+					continue
+				}
+				if open && !p.lazy() {
+					// The worker has expired while waiting for a caller.
+					p.spawn(ctx)
 					return
+				}
 
-				case p.requests <- req:
-					task := <-req.Request
-					if task != nil {
-						_ = req.SendResponse(task.Job(ctx))
-					}
+				p.live.Add(-1)
+				if open {
+					// A caller may have come while the worker was leaving.
+					p.grow()
 				}
-				req.Close()
+				return
 			}
-		}()
+
+			abandoned := false
+			if task := <-req.Request; task != nil {
+				w.busy()
+				_ = req.SendResponse(p.run(wctx, task))
+				w.tasks++
+				abandoned = !w.release()
+			}
+			req.Close()
+			p.leave()
+			if abandoned {
+				// A new worker has taken the place of this one while its task was stuck.
+				return
+			}
+
+			if p.retired(w) && ctx.Err() == nil {
+				p.spawn(ctx)
+				return
+			}
+		}
+	}()
+}
+
+// offer waits until a caller takes the request of a worker.
+// It reports false if ctx is done or, with open set, if the worker has expired, been idle for too long
+// or been nudged to check the pool settings.
+func (p *NonBlocking[T]) offer(ctx context.Context, w *worker, req *JobRequest[T]) (taken, open bool) {
+	select {
+	case p.requests <- req:
+		return true, true
+	default:
+	}
+	// No caller is waiting for the worker.
+	p.rest()
+
+	idle, stop := p.idleTimer()
+	defer stop()
+	nudged := w.wakeup
+
+	select {
+	case p.requests <- req:
+		return true, true
+	case <-ctx.Done():
+		return false, false
+	case <-w.expiry:
+		return false, true
+	case <-idle:
+		return false, true
+	case <-nudged:
+		w.nudged = true
+		return false, true
+	}
+}
+
+// grow spawns a worker if the pool is lazy (see WithIdleTimeout) and the waiting callers outnumber the idle workers.
+func (p *NonBlocking[T]) grow() {
+	if !p.lazy() {
+		return
 	}
 
-	p.start.Wait()
+	demand := p.waiting.Load()
+	if p.queue != nil {
+		demand += int64(p.queue.len())
+	}
+	if p.reserve(demand) {
+		p.spawn(p.ctx)
+	}
 }
 
 // Stop stops workers in the pool.
 func (p *NonBlocking[T]) Stop() {
+	p.halt()
 	p.cancel()
+	if p.queue != nil {
+		p.queue.close(ErrPoolStopped)
+	}
+
 	p.finish.Wait()
+	p.stopWatchdog()
+}
+
+// replace starts a worker in place of one abandoned by the watchdog with a stuck task.
+func (p *NonBlocking[T]) replace() {
+	p.spawn(p.ctx)
+	p.finish.Done()
+}
+
+// dispatch hands free workers over to the callers waiting in the pool queue.
+func (p *NonBlocking[T]) dispatch(ctx context.Context) {
+	defer p.finish.Done()
+
+	for {
+		if err := p.queue.wait(ctx); err != nil {
+			return
+		}
+
+		var req *JobRequest[T]
+		select {
+		case req = <-p.requests:
+		case <-ctx.Done():
+			return
+		}
+
+		for {
+			e := p.queue.pop()
+			if e == nil {
+				// Everybody has gone while waiting for the worker, it returns to the pool.
+				req.Close()
+				break
+			}
+
+			if err := e.ctx.Err(); err != nil {
+				e.grant <- err
+				continue
+			}
+
+			e.grant <- req
+			break
+		}
+	}
+}
+
+// Acquire waits for a free worker and returns a JobRequest[T] struct bound to it.
+// You have to send a task to the request or Close it, see RequestChan.
+// If the pool has a queue (see WithQueue and WithDeadlineScheduling), the caller waits in it and can be shed
+// with an OverloadError. After Stop it returns ErrPoolStopped.
+func (p *NonBlocking[T]) Acquire(ctx context.Context) (*JobRequest[T], error) {
+	return p.acquire(ctx, nil)
+}
+
+// acquire waits for a free worker for the task, which is nil if it is not known yet.
+func (p *NonBlocking[T]) acquire(ctx context.Context, task any) (*JobRequest[T], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.accepting(); err != nil {
+		p.reject(nil, err)
+		return nil, err
+	}
+
+	if p.queue == nil {
+		if p.lazy() {
+			select {
+			case req := <-p.requests:
+				return req, nil
+			default:
+			}
+
+			// No worker is free, the pool spawns one if it may.
+			p.waiting.Add(1)
+			defer p.waiting.Add(-1)
+			p.grow()
+		}
+
+		select {
+		case req := <-p.requests:
+			return req, nil
+		case <-p.stopped:
+			p.reject(nil, ErrPoolStopped)
+			return nil, ErrPoolStopped
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	e, err := p.queue.push(ctx, task)
+	if err != nil {
+		p.reject(nil, err)
+		return nil, err
+	}
+	p.grow()
+
+	select {
+	case g := <-e.grant:
+		return p.granted(g)
+
+	case <-ctx.Done():
+		if p.queue.remove(e) {
+			return nil, ctx.Err()
+		}
+
+		// The entry has just left the queue, the worker it got has to be returned.
+		if req, err := p.granted(<-e.grant); err == nil {
+			req.Close()
+		}
+
+		return nil, ctx.Err()
+	}
+}
+
+func (p *NonBlocking[T]) granted(g any) (*JobRequest[T], error) {
+	switch v := g.(type) {
+	case *JobRequest[T]:
+		return v, nil
+	case error:
+		if errors.Is(v, ErrOverloaded) || errors.Is(v, ErrDeadlineExceeded) {
+			p.reject(nil, v)
+		}
+		return nil, v
+	}
+
+	return nil, ErrPoolStopped
+}
+
+// Submit executes a task in a free worker and waits for the response.
+// It is a shortcut for Acquire followed by the JobRequest[T] handshake.
+// If ctx is done before the response arrives, the response carries the context error.
+func (p *NonBlocking[T]) Submit(ctx context.Context, task NonBlockingRunner[T]) JobResponse[T] {
+	return p.await(ctx, p.track(task, nil), task)
+}
+
+// Start executes a task in a free worker without waiting for the response.
+// The returned Handle reports the status of the task and cancels it, its value is
+// the JobResponse[T] of the task. The task is skipped if ctx is done before a worker takes it.
+func (p *NonBlocking[T]) Start(ctx context.Context, task NonBlockingRunner[T]) *Handle {
+	h := p.track(task, nil)
+	go p.await(ctx, h, task)
+
+	return h
+}
+
+// await executes a task tracked by a handle and waits for the response.
+func (p *NonBlocking[T]) await(ctx context.Context, h *Handle, task NonBlockingRunner[T]) JobResponse[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !h.bind(cancel) {
+		// The task has been cancelled before it was submitted.
+		return JobResponse[T]{Err: h.Err()}
+	}
+
+	resp := p.submit(ctx, tracked[T]{h, task})
+	// The worker records the response of a started task itself.
+	h.skip(resp.Err)
+
+	return resp
+}
+
+// submit hands a task over to a free worker and waits for the response.
+func (p *NonBlocking[T]) submit(ctx context.Context, task NonBlockingRunner[T]) JobResponse[T] {
+	req, err := p.acquire(ctx, task)
+	if err != nil {
+		return JobResponse[T]{Err: err}
+	}
+	defer req.Close()
+
+	select {
+	case req.Request <- task:
+	case <-ctx.Done():
+		return JobResponse[T]{Err: ctx.Err()}
+	}
+
+	select {
+	case resp := <-req.Response:
+		return resp
+	case <-ctx.Done():
+		return JobResponse[T]{Err: ctx.Err()}
+	}
+}
+
+// tracked is a task of a non-blocking pool which records its status in a handle.
+type tracked[T any] struct {
+	handle *Handle
+	task   NonBlockingRunner[T]
+}
+
+func (t tracked[T]) Job(ctx context.Context) JobResponse[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !t.handle.start(ctx, cancel) {
+		// The task has been cancelled while waiting for the worker.
+		return JobResponse[T]{Err: context.Canceled}
+	}
+
+	resp := t.task.Job(ctx)
+	t.handle.finish(resp, resp.Err)
+
+	return resp
+}
+
+func (t tracked[T]) unwrap() any {
+	return t.task
+}
+
+// run executes a task as soon as it fits into the pool budgets.
+// If the task is rejected or ctx is done before it is admitted, the response carries the error.
+func (p *NonBlocking[T]) run(ctx context.Context, task NonBlockingRunner[T]) JobResponse[T] {
+	// The reject handler gets the task of the caller, not its tracking wrapper.
+	var admitted any = task
+	if t, ok := task.(tracked[T]); ok {
+		admitted = t.task
+	}
+
+	release, err := p.admit(ctx, admitted)
+	if err != nil {
+		return JobResponse[T]{Err: err}
+	}
+	defer release()
+
+	var resp JobResponse[T]
+	p.execute(ctx, task, func(ctx context.Context) error {
+		resp = task.Job(ctx)
+		return resp.Err
+	})
+
+	return resp
 }
 
 // RequestChan returns a request channel for executing a task in a worker.
@@ -107,9 +480,10 @@ func (p *NonBlocking[T]) RequestChan() chan *JobRequest[T] {
 
 // NewJobRequest creates a new JobRequest[T] struct which is used to interact with a worker.
 func NewJobRequest[T any]() *JobRequest[T] {
+	// The response is buffered, so a worker does not wait for a caller who has gone.
 	return &JobRequest[T]{
 		Request:  make(chan NonBlockingRunner[T]),
-		Response: make(chan JobResponse[T]),
+		Response: make(chan JobResponse[T], 1),
 		closed:   false,
 	}
 }
@@ -2,15 +2,17 @@ package pool_test
 
 import (
 	"context"
-	"math/rand"
+	"expvar"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
 )
 
 type count struct {
@@ -26,8 +28,6 @@ func (a *count) Job(context.Context) pool.JobResponse[string] {
 	a.cnt.value++
 	a.cnt.mu.Unlock()
 
-	time.Sleep(time.Duration(rand.Intn(10000)) * time.Microsecond)
-
 	return pool.JobResponse[string]{
 		Value: strconv.Itoa(v),
 	}
@@ -35,14 +35,30 @@ func (a *count) Job(context.Context) pool.JobResponse[string] {
 
 func TestNonBlocking_Run(t *testing.T) {
 	t.Run("Successful 99 tasks run", func(t *testing.T) {
+		pooltest.CheckLeaks(t)
 		const total = 99
 
 		var cnt counter
 		var wg sync.WaitGroup
+		sched := pooltest.NewScheduler()
 
-		workers := pool.NewNonBlocking[string](10)
+		workers := pool.NewNonBlocking[string](10, sched.Option())
 		workers.Run(context.Background())
 
+		// The workers hold their tasks, so the next request comes only after a step.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		stepped := make(chan error, 1)
+		go func() {
+			for i := 0; i < total; i++ {
+				if _, err := sched.Step(ctx); err != nil {
+					stepped <- err
+					return
+				}
+			}
+			stepped <- nil
+		}()
+
 		executed := 0
 		requests := workers.RequestChan()
 
@@ -59,6 +75,7 @@ func TestNonBlocking_Run(t *testing.T) {
 
 			executed++
 		}
+		require.NoError(t, <-stepped)
 		wg.Wait()
 		workers.Stop()
 
@@ -74,6 +91,9 @@ func TestNonBlocking_Run(t *testing.T) {
 		received := 0
 		requests := workers.RequestChan()
 
+		// A closed request frees its worker at once, the deadline only bounds a broken pool.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 		for i := 0; i < total; i++ {
 			select {
 			case req := <-requests:
@@ -81,11 +101,26 @@ func TestNonBlocking_Run(t *testing.T) {
 				received++
 				req.Close()
 
-			case <-time.After(100 * time.Millisecond):
-				t.Logf("worker %d missed\n", i)
+			case <-ctx.Done():
+				t.Fatalf("worker %d missed", i)
 			}
 		}
 
 		assert.Equal(t, total, received)
 	})
 }
+
+func TestNewNonBlocking(t *testing.T) {
+	t.Run("Tenants are not supported", func(t *testing.T) {
+		assert.PanicsWithValue(t, "pool: WithTenants is not supported by the non-blocking pool", func() {
+			pool.NewNonBlocking[string](1, pool.WithTenants(pool.TenantConfig{}))
+		})
+	})
+
+	t.Run("Rejected options are not published", func(t *testing.T) {
+		assert.Panics(t, func() {
+			pool.NewNonBlocking[string](1, pool.WithName("rejected"), pool.WithTenants(pool.TenantConfig{}))
+		})
+		assert.Nil(t, expvar.Get("pool.rejected"))
+	})
+}
@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+)
+
+var (
+	// published keeps the running pools published with expvar by their names.
+	published = make(map[string]*core)
+	// exported keeps the names of the expvar variables, expvar cannot remove a variable.
+	exported    = make(map[string]bool)
+	publishedMu sync.Mutex
+)
+
+// publish publishes the statistic values of a named pool with expvar.
+// The expvar variable is created once per name and shows the latest running pool with the name.
+func publish(name string, c *core) {
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+
+	published[name] = c
+	if exported[name] {
+		return
+	}
+	exported[name] = true
+
+	expvar.Publish("pool."+name, expvar.Func(func() any {
+		publishedMu.Lock()
+		c, ok := published[name]
+		publishedMu.Unlock()
+
+		if !ok {
+			return nil
+		}
+		return c.Stats()
+	}))
+}
+
+// unpublish lets a stopped pool go, unless a later pool with the same name has replaced it.
+func (c *core) unpublish() {
+	if c.cfg.name == "" {
+		return
+	}
+
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+
+	if published[c.cfg.name] == c {
+		delete(published, c.cfg.name)
+	}
+}
+
+// withSection returns the options of a section of a named pool, the section is named "<name>/<section>".
+func withSection(opts []Option, section string) []Option {
+	cfg := newConfig(opts)
+	if cfg.name == "" {
+		return opts
+	}
+
+	return append(opts[:len(opts):len(opts)], WithName(cfg.name+"/"+section))
+}
+
+// label runs the job of a task with the profiler labels of a named pool.
+func (c *core) label(ctx context.Context, task any, job func(ctx context.Context) error) error {
+	if c.cfg.name == "" {
+		return job(ctx)
+	}
+
+	id, _ := WorkerID(ctx)
+	labels := pprof.Labels("pool", c.cfg.name, "task", taskType(task), "worker", strconv.Itoa(id))
+
+	var err error
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		err = job(ctx)
+	})
+
+	return err
+}
+
+// taskType returns the type of a task, the innermost one if the task wraps others.
+func taskType(task any) string {
+	return fmt.Sprintf("%T", innermost(task))
+}
@@ -0,0 +1,107 @@
+package pool_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// labelProbe reports the profiler labels of its context.
+type labelProbe struct{}
+
+func (labelProbe) Job(ctx context.Context) pool.JobResponse[map[string]string] {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+
+	return pool.JobResponse[map[string]string]{Value: labels}
+}
+
+func TestWithName(t *testing.T) {
+	t.Run("Profiler labels", func(t *testing.T) {
+		workers := pool.NewNonBlocking[map[string]string](1, pool.WithName("labels"))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		resp := workers.Submit(context.Background(), labelProbe{})
+		require.NoError(t, resp.Err)
+		assert.Equal(t, map[string]string{
+			"pool":   "labels",
+			"task":   "pool_test.labelProbe",
+			"worker": "1",
+		}, resp.Value)
+	})
+
+	t.Run("No labels without a name", func(t *testing.T) {
+		workers := pool.NewNonBlocking[map[string]string](1)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		resp := workers.Submit(context.Background(), labelProbe{})
+		require.NoError(t, resp.Err)
+		assert.Empty(t, resp.Value)
+	})
+
+	t.Run("expvar", func(t *testing.T) {
+		first := pool.New(1, pool.WithName("expvar"))
+		first.Run(context.Background())
+		first.Stop()
+
+		// The name is published once and shows the latest pool.
+		second := pool.New(3, pool.WithName("expvar"))
+		second.Run(context.Background())
+		defer second.Stop()
+
+		v := expvar.Get("pool.expvar")
+		require.NotNil(t, v)
+
+		var s pool.Stats
+		require.NoError(t, json.Unmarshal([]byte(v.String()), &s))
+		assert.Equal(t, 3, s.Workers)
+	})
+
+	t.Run("Stopped pool is not shown", func(t *testing.T) {
+		workers := pool.New(1, pool.WithName("stopped"))
+		workers.Run(context.Background())
+		require.NotNil(t, expvar.Get("pool.stopped"))
+		workers.Stop()
+
+		assert.Equal(t, "null", expvar.Get("pool.stopped").String())
+	})
+
+	t.Run("Sections", func(t *testing.T) {
+		workers := pool.NewPartitioned([]pool.Partition{{Name: "a", Workers: 1}, {Name: "b", Workers: 2}}, 3, pool.WithName("sections"))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		for name, size := range map[string]int{"a": 1, "b": 2, "overflow": 3} {
+			v := expvar.Get("pool.sections/" + name)
+			require.NotNil(t, v, name)
+
+			var s pool.Stats
+			require.NoError(t, json.Unmarshal([]byte(v.String()), &s))
+			assert.Equal(t, size, s.Workers, name)
+		}
+	})
+
+	t.Run("Stages", func(t *testing.T) {
+		p := pool.NewPipeline(pool.PipelineConfig{},
+			pool.Stage{Name: "parse", Func: parseStage, Options: []pool.Option{pool.WithName("stages")}},
+			pool.Stage{Name: "sink", Func: func(context.Context, any, func(any)) error { return nil }, Options: []pool.Option{pool.WithName("stages")}},
+		)
+		p.Run(context.Background())
+		defer p.Stop()
+
+		assert.NotNil(t, expvar.Get("pool.stages/parse"))
+		assert.NotNil(t, expvar.Get("pool.stages/sink"))
+	})
+}
@@ -0,0 +1,240 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a worker pool created by New or NewNonBlocking.
+type Option func(*config)
+
+// config keeps the optional settings of a worker pool.
+type config struct {
+	capacity      int64
+	memoryBudget  int64
+	memoryMode    AdmissionMode
+	heapLimit     uint64
+	heapMode      AdmissionMode
+	onReject      func(task any, err error)
+	limiter       Limiter
+	queue         *QueueConfig
+	tenants       *TenantConfig
+	deadline      *DeadlineConfig
+	workerState   func() any
+	maxTasks      int
+	maxLifetime   time.Duration
+	onWorkerStart func(ctx context.Context)
+	onWorkerStop  func(ctx context.Context)
+	idleTimeout   time.Duration
+	thread        *ThreadConfig
+	watchdog      *WatchdogConfig
+	name          string
+	recorder      *Recorder
+	health        *HealthConfig
+	clock         Clock
+	taskHook      func(ctx context.Context, task any) func()
+}
+
+// newConfig applies the options to a new config.
+func newConfig(opts []Option) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithCapacity sets a budget for the sum of weights of simultaneously running tasks.
+// A task declares its weight by implementing the Weigher interface, other tasks weigh 1.
+// Tasks are admitted in the order they reach the workers, so a heavy task is not
+// overtaken by lighter ones while it waits for the budget.
+// A capacity less than 1 means the budget is not limited.
+func WithCapacity(capacity int64) Option {
+	return func(c *config) {
+		c.capacity = capacity
+	}
+}
+
+// WithMemoryBudget sets a budget in bytes for the sum of memory estimates of running tasks.
+// A task declares its estimate by implementing the MemoryEstimator interface,
+// other tasks are not limited by the budget.
+// The mode defines whether a task which does not fit is delayed or rejected.
+func WithMemoryBudget(bytes int64, mode AdmissionMode) Option {
+	return func(c *config) {
+		c.memoryBudget = bytes
+		c.memoryMode = mode
+	}
+}
+
+// WithHeapLimit makes the pool sample the heap usage before starting a task.
+// While the heap is larger than the limit, new tasks are delayed or rejected according to the mode.
+func WithHeapLimit(bytes uint64, mode AdmissionMode) Option {
+	return func(c *config) {
+		c.heapLimit = bytes
+		c.heapMode = mode
+	}
+}
+
+// WithRejectHandler sets a function called for every task the pool skips without running it,
+// e.g. because it did not fit into a budget or the pool context was done.
+// Non-blocking pools also deliver the error to the caller in JobResponse.
+// The task is nil for a caller shed from the queue before it got a worker.
+func WithRejectHandler(fn func(task any, err error)) Option {
+	return func(c *config) {
+		c.onReject = fn
+	}
+}
+
+// WithLimiter makes the pool adjust the number of simultaneously running tasks with the limiter.
+// The number of workers becomes the upper bound of the limit.
+func WithLimiter(limiter Limiter) Option {
+	return func(c *config) {
+		c.limiter = limiter
+	}
+}
+
+// WithQueue makes the callers of NonBlocking.Acquire and NonBlocking.Submit wait for a worker
+// in a queue managed by the pool, which sheds them with an OverloadError when
+// the waiting time stays above the target.
+func WithQueue(cfg QueueConfig) Option {
+	return func(c *config) {
+		c.queue = &cfg
+	}
+}
+
+// WithTenants makes the blocking pool queue tasks per tenant (see Tenanter) and serve the tenants
+// with deficit round robin, so each of them gets its weighted share of the workers.
+// The weight of a task (see Weigher) is its cost in the scheduling.
+// It is ignored if the pool has WithDeadlineScheduling. NewNonBlocking panics with this option.
+func WithTenants(cfg TenantConfig) Option {
+	return func(c *config) {
+		c.tenants = &cfg
+	}
+}
+
+// WithDeadlineScheduling makes the pool serve tasks in the order of the deadlines of their
+// contexts (earliest deadline first). Tasks whose deadline has passed or cannot be met
+// (see DurationEstimator) are skipped with ErrDeadlineExceeded before they reach Job.
+// The blocking pool takes the context of Submit. The non-blocking pool orders the callers
+// of Acquire and Submit in its queue (see WithQueue) and returns them ErrDeadlineExceeded.
+func WithDeadlineScheduling(cfg DeadlineConfig) Option {
+	return func(c *config) {
+		c.deadline = &cfg
+	}
+}
+
+// WithWorkerState gives every worker its own state created by newState when the worker starts,
+// so tasks can reuse expensive resources such as clients, buffers or connections.
+// A task gets the state of its worker with WorkerState. If the state implements io.Closer,
+// it is closed when the worker stops.
+func WithWorkerState[S any](newState func() S) Option {
+	return func(c *config) {
+		c.workerState = func() any {
+			return newState()
+		}
+	}
+}
+
+// WithMaxTasks makes the pool replace a worker after it has run n tasks.
+// The replacement starts before the retired worker is torn down, so the number of workers stays the same.
+// An n less than 1 means workers are not replaced.
+func WithMaxTasks(n int) Option {
+	return func(c *config) {
+		c.maxTasks = n
+	}
+}
+
+// WithMaxLifetime makes the pool replace a worker which has been running for d.
+// A busy worker is replaced when it finishes its task. Like WithMaxTasks, the replacement
+// starts before the retired worker is torn down.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(c *config) {
+		c.maxLifetime = d
+	}
+}
+
+// WithWorkerHooks sets functions called when a worker starts and when it stops or is retired.
+// The ctx carries the worker ID and state (see WorkerID and WorkerState). Either function may be nil.
+// The stop hook is called before the worker state is closed.
+func WithWorkerHooks(onStart, onStop func(ctx context.Context)) Option {
+	return func(c *config) {
+		c.onWorkerStart = onStart
+		c.onWorkerStop = onStop
+	}
+}
+
+// WithIdleTimeout makes the pool spawn workers on demand, up to the number of workers,
+// instead of starting all of them in Run. A worker which waits for a task longer than d exits.
+// Workers are spawned for the callers of Execute, Submit and Acquire; a caller reading
+// NonBlocking.RequestChan directly does not make the pool spawn a worker.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.idleTimeout = d
+	}
+}
+
+// WithThreads locks every worker to its own OS thread and applies the CPU set and the nice level
+// of the configuration to the thread. It isolates CPU-bound workers from the other goroutines
+// of the process. CPU sets and nice levels are supported on Linux only.
+func WithThreads(cfg ThreadConfig) Option {
+	return func(c *config) {
+		c.thread = &cfg
+	}
+}
+
+// WithWatchdog makes the pool watch the tasks added with Execute, Submit or Start and act on
+// those running longer than the threshold (see WatchdogConfig).
+func WithWatchdog(cfg WatchdogConfig) Option {
+	return func(c *config) {
+		if cfg.Interval <= 0 {
+			cfg.Interval = cfg.Threshold / 4
+		}
+		c.watchdog = &cfg
+	}
+}
+
+// WithName names the pool. The statistic values of a named pool are published with expvar
+// under "pool.<name>", and its tasks run with the runtime/pprof labels "pool" (the name),
+// "task" (the type of the task) and "worker" (the worker ID), so CPU profiles tell the tasks apart.
+// A pool created later with the same name replaces the earlier one in expvar, a stopped pool
+// is not shown. The sections of a partitioned pool and the stages of a pipeline are named
+// "<name>/<partition>" (the overflow section "<name>/overflow") and "<name>/<stage>".
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithRecorder records the timeline of the tasks added with Execute, Submit or Start in the recorder.
+// Several pools may share a recorder.
+func WithRecorder(r *Recorder) Option {
+	return func(c *config) {
+		c.recorder = r
+	}
+}
+
+// WithHealth sets the thresholds Health reports the pool overloaded above.
+func WithHealth(cfg HealthConfig) Option {
+	return func(c *config) {
+		c.health = &cfg
+	}
+}
+
+// WithClock makes the pool tell the time and run its timers with the clock, e.g. with the fake clock
+// of the pooltest package in tests. The clock serves the queue, the idle and lifetime timers of the workers,
+// the watchdog, the batching and the hedging delays.
+func WithClock(clock Clock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// WithTaskHook sets a function called in the worker right before every task, the function it returns
+// (if not nil) is called right after the task. The task starts when hook returns, so the hook can hold it,
+// e.g. the scheduler of the pooltest package does to run the tasks step by step.
+func WithTaskHook(hook func(ctx context.Context, task any) func()) Option {
+	return func(c *config) {
+		c.taskHook = hook
+	}
+}
@@ -0,0 +1,165 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+var (
+	ErrUnknownPartition = fmt.Errorf("unknown partition")
+)
+
+// Partition describes a named section of a partitioned pool.
+type Partition struct {
+	// Name identifies the partition in Execute.
+	Name string
+	// Workers is the number of workers reserved for the partition, at least 1.
+	Workers int
+	// MaxBorrow is the number of tasks of the partition which may run in the overflow
+	// section at once. Zero means the partition does not borrow.
+	MaxBorrow int
+}
+
+// PartitionedStats keeps statistic values about a partitioned pool.
+type PartitionedStats struct {
+	// Partitions keeps the values of the reserved workers of every partition.
+	Partitions map[string]Stats
+	// Borrowed is the number of overflow workers every partition occupies now.
+	Borrowed map[string]int
+	// Overflow keeps the values of the shared overflow section.
+	Overflow Stats
+}
+
+// Partitioned hosts several partitions (bulkheads) with reserved workers, so a flood of tasks
+// in one partition never takes the workers of the others.
+// A partition whose workers are busy may borrow idle workers of a shared overflow section.
+type Partitioned struct {
+	parts    map[string]*partition
+	overflow *Pool
+}
+
+// partition keeps the reserved workers of a partition and the number of borrowed ones.
+type partition struct {
+	cfg      Partition
+	pool     *Pool
+	borrowed atomic.Int64
+}
+
+// NewPartitioned creates a new partitioned worker pool with an overflow section
+// of overflowCnt workers shared by the partitions. The options apply to every section,
+// a name given with WithName becomes "<name>/<partition>" and "<name>/overflow".
+func NewPartitioned(partitions []Partition, overflowCnt int, opts ...Option) *Partitioned {
+	p := &Partitioned{
+		parts: make(map[string]*partition, len(partitions)),
+	}
+
+	for _, cfg := range partitions {
+		if cfg.Workers < 1 {
+			cfg.Workers = 1
+		}
+
+		p.parts[cfg.Name] = &partition{
+			cfg:  cfg,
+			pool: New(cfg.Workers, withSection(opts, cfg.Name)...),
+		}
+	}
+
+	if overflowCnt > 0 {
+		p.overflow = New(overflowCnt, withSection(opts, "overflow")...)
+	}
+
+	return p
+}
+
+// Run starts workers in all sections of the pool.
+func (p *Partitioned) Run(ctx context.Context) {
+	for _, part := range p.parts {
+		part.pool.Run(ctx)
+	}
+
+	if p.overflow != nil {
+		p.overflow.Run(ctx)
+	}
+}
+
+// Stop stops workers in all sections of the pool.
+func (p *Partitioned) Stop() {
+	for _, part := range p.parts {
+		part.pool.Stop()
+	}
+
+	if p.overflow != nil {
+		p.overflow.Stop()
+	}
+}
+
+// Execute adds a new task in the tasks queue of a partition.
+// If all reserved workers of the partition are busy, the task goes to an idle worker
+// of the overflow section, unless the partition has already borrowed MaxBorrow of them.
+// Lazy sections (see WithIdleTimeout) spawn a worker before they count as busy.
+func (p *Partitioned) Execute(partition string, task Runner) error {
+	part, ok := p.parts[partition]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPartition, partition)
+	}
+
+	if part.busy() && p.overflow != nil && part.borrow() {
+		// The overflow worker is given back when the task is finished or rejected.
+		if p.overflow.lend(task, part.giveBack) {
+			return nil
+		}
+		part.giveBack()
+	}
+
+	part.pool.Execute(task)
+	return nil
+}
+
+// Stats returns the current statistic values of every section of the pool.
+func (p *Partitioned) Stats() PartitionedStats {
+	s := PartitionedStats{
+		Partitions: make(map[string]Stats, len(p.parts)),
+		Borrowed:   make(map[string]int, len(p.parts)),
+	}
+
+	for name, part := range p.parts {
+		s.Partitions[name] = part.pool.Stats()
+		s.Borrowed[name] = int(part.borrowed.Load())
+	}
+
+	if p.overflow != nil {
+		s.Overflow = p.overflow.Stats()
+	}
+
+	return s
+}
+
+// busy reports whether all reserved workers of the partition are busy
+// and it may not spawn one more (see WithIdleTimeout).
+func (part *partition) busy() bool {
+	if part.pool.idle.Load() > 0 {
+		return false
+	}
+
+	return !part.pool.lazy() || part.pool.live.Load() >= part.pool.size.Load()
+}
+
+// borrow reserves an overflow worker for the partition if it is allowed to take one more.
+func (part *partition) borrow() bool {
+	for {
+		n := part.borrowed.Load()
+		if n >= int64(part.cfg.MaxBorrow) {
+			return false
+		}
+
+		if part.borrowed.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// giveBack returns an overflow worker borrowed by the partition.
+func (part *partition) giveBack() {
+	part.borrowed.Add(-1)
+}
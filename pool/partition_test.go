@@ -0,0 +1,185 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// blocked is a task which runs until it is released.
+type blocked struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlocked() blocked {
+	return blocked{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (b blocked) Job(context.Context) {
+	close(b.started)
+	<-b.release
+}
+
+type signal chan struct{}
+
+func (s signal) Job(context.Context) {
+	close(s)
+}
+
+func TestPartitioned_Execute(t *testing.T) {
+	t.Run("Flood of batch tasks does not take interactive workers", func(t *testing.T) {
+		workers := pool.NewPartitioned([]pool.Partition{
+			{Name: "interactive", Workers: 1},
+			{Name: "batch", Workers: 1, MaxBorrow: 1},
+		}, 2)
+		workers.Run(context.Background())
+
+		reserved, overflow := newBlocked(), newBlocked()
+		require.NoError(t, workers.Execute("batch", reserved))
+		<-reserved.started
+		require.NoError(t, workers.Execute("batch", overflow))
+		<-overflow.started
+
+		// The batch partition has borrowed all it may, the next task waits for its own worker.
+		queued := newBlocked()
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, workers.Execute("batch", queued))
+		}()
+
+		stats := workers.Stats()
+		assert.Equal(t, 1, stats.Borrowed["batch"])
+		assert.Equal(t, 1, stats.Overflow.Running)
+
+		done := make(signal)
+		require.NoError(t, workers.Execute("interactive", done))
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("interactive task has not run")
+		}
+
+		close(reserved.release)
+		close(overflow.release)
+		<-queued.started
+		close(queued.release)
+		wg.Wait()
+		workers.Stop()
+
+		stats = workers.Stats()
+		assert.Equal(t, uint64(1), stats.Partitions["interactive"].Completed)
+		assert.Equal(t, uint64(3), stats.Partitions["batch"].Completed+stats.Overflow.Completed)
+		assert.Equal(t, 0, stats.Borrowed["batch"])
+	})
+
+	t.Run("Unknown partition", func(t *testing.T) {
+		workers := pool.NewPartitioned([]pool.Partition{{Name: "interactive", Workers: 1}}, 0)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		err := workers.Execute("batch", make(signal))
+		assert.ErrorIs(t, err, pool.ErrUnknownPartition)
+	})
+
+	t.Run("Rejected borrowed task gives the worker back", func(t *testing.T) {
+		workers := pool.NewPartitioned([]pool.Partition{
+			{Name: "batch", Workers: 1, MaxBorrow: 2},
+		}, 2, pool.WithMemoryBudget(100, pool.AdmissionReject))
+		workers.Run(context.Background())
+
+		reserved, first := newBlocked(), hungry{newBlocked()}
+		require.NoError(t, workers.Execute("batch", reserved))
+		<-reserved.started
+		require.NoError(t, workers.Execute("batch", first))
+		<-first.started
+
+		// The first borrowed task takes the whole budget of the overflow section.
+		require.NoError(t, workers.Execute("batch", hungry{newBlocked()}))
+		require.Eventually(t, func() bool { return workers.Stats().Overflow.Rejected == 1 }, time.Second, time.Millisecond)
+		require.Eventually(t, func() bool { return workers.Stats().Borrowed["batch"] == 1 }, time.Second, time.Millisecond)
+
+		// The partition may borrow again.
+		second := newBlocked()
+		require.NoError(t, workers.Execute("batch", second))
+		<-second.started
+		assert.Equal(t, 2, workers.Stats().Borrowed["batch"])
+
+		close(reserved.release)
+		close(first.release)
+		close(second.release)
+		workers.Stop()
+		assert.Equal(t, 0, workers.Stats().Borrowed["batch"])
+	})
+
+	t.Run("Lazy sections", func(t *testing.T) {
+		workers := pool.NewPartitioned([]pool.Partition{
+			{Name: "batch", Workers: 1, MaxBorrow: 1},
+		}, 1, pool.WithIdleTimeout(time.Minute))
+		workers.Run(context.Background())
+
+		// The partition spawns its own worker first.
+		reserved := newBlocked()
+		require.NoError(t, workers.Execute("batch", reserved))
+		<-reserved.started
+		assert.Equal(t, 0, workers.Stats().Borrowed["batch"])
+
+		// The overflow section spawns a worker for the borrowed task.
+		overflow := newBlocked()
+		require.NoError(t, workers.Execute("batch", overflow))
+		select {
+		case <-overflow.started:
+		case <-time.After(time.Second):
+			t.Fatal("the task waits for the reserved worker")
+		}
+		assert.Equal(t, 1, workers.Stats().Borrowed["batch"])
+
+		close(overflow.release)
+		close(reserved.release)
+		workers.Stop()
+	})
+
+	t.Run("Scheduling sections lend only free workers", func(t *testing.T) {
+		workers := pool.NewPartitioned([]pool.Partition{
+			{Name: "batch", Workers: 1, MaxBorrow: 2},
+		}, 1, pool.WithTenants(pool.TenantConfig{}))
+		workers.Run(context.Background())
+
+		reserved, overflow := newBlocked(), newBlocked()
+		require.NoError(t, workers.Execute("batch", reserved))
+		<-reserved.started
+		require.NoError(t, workers.Execute("batch", overflow))
+		<-overflow.started
+
+		// The overflow worker is busy, the task is queued in its own partition.
+		require.NoError(t, workers.Execute("batch", signal(make(chan struct{}))))
+		stats := workers.Stats()
+		assert.Equal(t, 1, stats.Borrowed["batch"])
+		assert.Equal(t, 0, stats.Overflow.Queued)
+		assert.Equal(t, 1, stats.Partitions["batch"].Queued)
+
+		close(overflow.release)
+		close(reserved.release)
+		workers.Stop()
+	})
+}
+
+// hungry is a blocked task which needs the whole memory budget of a section.
+type hungry struct {
+	blocked
+}
+
+func (hungry) MemoryEstimate() int64 {
+	return 100
+}
@@ -0,0 +1,205 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// StageFunc processes an item in a stage of a pipeline. It passes the results to the next stage
+// with emit, which waits while the next stage is full. A stage may emit any number of items,
+// emit of the last stage discards them.
+type StageFunc func(ctx context.Context, item any, emit func(item any)) error
+
+// Stage describes a stage of a pipeline.
+type Stage struct {
+	// Name identifies the stage in the errors and statistic values.
+	Name string
+	// Workers is the number of workers of the stage, at least 1.
+	Workers int
+	// MaxQueued is the number of items waiting for a worker of the stage.
+	// When the queue is full, the previous stage waits. Zero means an item is handed
+	// straight to a free worker.
+	MaxQueued int
+	// Func processes the items of the stage.
+	Func StageFunc
+	// Options configure the pool of the stage. A name given with WithName becomes "<name>/<stage>".
+	Options []Option
+}
+
+// PipelineConfig keeps the settings of a pipeline.
+type PipelineConfig struct {
+	// OnError is called for an item failed in a stage. If it returns an error, the pipeline
+	// is aborted with it. By default, the first error aborts the pipeline.
+	OnError func(stage string, item any, err error) error
+}
+
+// StageError is an error of an item in a stage of a pipeline.
+type StageError struct {
+	Stage string
+	Item  any
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return "stage " + e.Stage + ": " + e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline passes items through a chain of stages, each of them runs in its own pool.
+// A full stage makes the previous one wait, so the items do not pile up between the stages.
+type Pipeline struct {
+	cfg     PipelineConfig
+	stages  []*pipelineStage
+	pending sync.WaitGroup
+	cancel  context.CancelFunc
+	err     error
+	mu      sync.Mutex
+}
+
+// pipelineStage is a stage with its pool.
+type pipelineStage struct {
+	cfg  Stage
+	pool *Pool
+	next *pipelineStage
+}
+
+// stageTask processes an item in a stage.
+type stageTask struct {
+	pipeline *Pipeline
+	stage    *pipelineStage
+	item     any
+}
+
+// NewPipeline creates a new pipeline of the stages.
+func NewPipeline(cfg PipelineConfig, stages ...Stage) *Pipeline {
+	if cfg.OnError == nil {
+		cfg.OnError = func(stage string, item any, err error) error {
+			return &StageError{Stage: stage, Item: item, Err: err}
+		}
+	}
+
+	p := &Pipeline{cfg: cfg}
+	for _, s := range stages {
+		if s.Workers < 1 {
+			s.Workers = 1
+		}
+
+		opts := withSection(s.Options, s.Name)
+		if s.MaxQueued > 0 {
+			// A single tenant queue bounds the items waiting for the workers.
+			opts = append(opts[:len(opts):len(opts)], WithTenants(TenantConfig{MaxQueued: s.MaxQueued}))
+		}
+
+		p.stages = append(p.stages, &pipelineStage{cfg: s, pool: New(s.Workers, opts...)})
+	}
+	for i := 0; i < len(p.stages)-1; i++ {
+		p.stages[i].next = p.stages[i+1]
+	}
+
+	return p
+}
+
+// Run starts the workers of all stages.
+func (p *Pipeline) Run(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	for _, s := range p.stages {
+		s.pool.Run(ctx)
+	}
+}
+
+// Push adds an item to the first stage, waiting while the stage is full.
+// It returns the error the pipeline is aborted with, or the context error if ctx is done.
+func (p *Pipeline) Push(ctx context.Context, item any) error {
+	if err := p.Err(); err != nil {
+		return err
+	}
+	if len(p.stages) == 0 {
+		return nil
+	}
+
+	return p.send(ctx, p.stages[0], item)
+}
+
+// Stop waits until all pushed items pass the pipeline and stops the workers of all stages.
+// It returns the error the pipeline is aborted with.
+func (p *Pipeline) Stop() error {
+	p.pending.Wait()
+
+	for _, s := range p.stages {
+		s.pool.Stop()
+	}
+	p.cancel()
+
+	return p.Err()
+}
+
+// Err returns the error the pipeline is aborted with, or nil.
+func (p *Pipeline) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+// Stats returns the current statistic values of the pool of every stage.
+func (p *Pipeline) Stats() map[string]Stats {
+	s := make(map[string]Stats, len(p.stages))
+	for _, stage := range p.stages {
+		s[stage.cfg.Name] = stage.pool.Stats()
+	}
+
+	return s
+}
+
+// send adds an item to a stage. The item is pending until its task is finished or skipped by the pool
+// of the stage.
+func (p *Pipeline) send(ctx context.Context, s *pipelineStage, item any) error {
+	p.pending.Add(1)
+	_, err := s.pool.start(ctx, Fallible(stageTask{p, s, item}), p.pending.Done)
+
+	return err
+}
+
+// abort cancels the items which have not been processed yet.
+func (p *Pipeline) abort(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
+
+// Describe describes the item in the snapshots of the pool of the stage.
+func (t stageTask) Describe() string {
+	return fmt.Sprintf("stage %s: %v", t.stage.cfg.Name, t.item)
+}
+
+// Job processes the item, the pool of the stage counts the failed items.
+func (t stageTask) Job(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		// The pipeline is aborted, the item is dropped.
+		return err
+	}
+
+	emit := func(item any) {
+		if t.stage.next != nil {
+			_ = t.pipeline.send(ctx, t.stage.next, item)
+		}
+	}
+
+	err := t.stage.cfg.Func(ctx, t.item, emit)
+	if err != nil {
+		if abortErr := t.pipeline.cfg.OnError(t.stage.cfg.Name, t.item, err); abortErr != nil {
+			t.pipeline.abort(abortErr)
+		}
+	}
+
+	return err
+}
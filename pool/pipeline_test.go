@@ -0,0 +1,170 @@
+package pool_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+func parseStage(_ context.Context, item any, emit func(any)) error {
+	n, err := strconv.Atoi(item.(string))
+	if err != nil {
+		return err
+	}
+
+	emit(n)
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	t.Run("Items pass all stages", func(t *testing.T) {
+		var sum atomic.Int64
+
+		p := pool.NewPipeline(pool.PipelineConfig{},
+			pool.Stage{Name: "parse", Workers: 2, Func: parseStage},
+			pool.Stage{Name: "square", Workers: 3, MaxQueued: 2, Func: func(_ context.Context, item any, emit func(any)) error {
+				// Odd numbers are filtered out, even ones are emitted twice.
+				if n := item.(int); n%2 == 0 {
+					emit(n * n)
+					emit(n * n)
+				}
+				return nil
+			}},
+			pool.Stage{Name: "sum", Func: func(_ context.Context, item any, _ func(any)) error {
+				sum.Add(int64(item.(int)))
+				return nil
+			}},
+		)
+		p.Run(context.Background())
+
+		for i := 1; i <= 10; i++ {
+			require.NoError(t, p.Push(context.Background(), strconv.Itoa(i)))
+		}
+		require.NoError(t, p.Stop())
+
+		assert.Equal(t, int64(2*(4+16+36+64+100)), sum.Load())
+		stats := p.Stats()
+		assert.Equal(t, uint64(10), stats["parse"].Completed)
+		assert.Equal(t, uint64(10), stats["square"].Completed)
+		assert.Equal(t, uint64(10), stats["sum"].Completed)
+	})
+
+	t.Run("Full stage makes the previous one wait", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var once sync.Once
+
+		p := pool.NewPipeline(pool.PipelineConfig{},
+			pool.Stage{Name: "forward", Func: func(_ context.Context, item any, emit func(any)) error {
+				emit(item)
+				return nil
+			}},
+			pool.Stage{Name: "slow", MaxQueued: 1, Func: func(context.Context, any, func(any)) error {
+				once.Do(func() { close(started) })
+				<-release
+				return nil
+			}},
+		)
+		p.Run(context.Background())
+
+		// The slow worker, its queue and the forward worker take an item each.
+		for i := 0; i < 3; i++ {
+			require.NoError(t, p.Push(context.Background(), i))
+		}
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, p.Push(ctx, 3), context.DeadlineExceeded)
+
+		close(release)
+		require.NoError(t, p.Stop())
+		assert.Equal(t, uint64(3), p.Stats()["slow"].Completed)
+	})
+
+	t.Run("Error aborts the pipeline", func(t *testing.T) {
+		var mu sync.Mutex
+		var parsed []int
+
+		p := pool.NewPipeline(pool.PipelineConfig{},
+			pool.Stage{Name: "parse", Func: parseStage},
+			pool.Stage{Name: "collect", Func: func(_ context.Context, item any, _ func(any)) error {
+				mu.Lock()
+				parsed = append(parsed, item.(int))
+				mu.Unlock()
+				return nil
+			}},
+		)
+		p.Run(context.Background())
+
+		require.NoError(t, p.Push(context.Background(), "1"))
+		// The abort drops the items which have not been collected yet.
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(parsed) == 1
+		}, time.Second, time.Millisecond)
+		require.NoError(t, p.Push(context.Background(), "x"))
+		require.Eventually(t, func() bool { return p.Err() != nil }, time.Second, time.Millisecond)
+
+		var stageErr *pool.StageError
+		assert.ErrorAs(t, p.Push(context.Background(), "2"), &stageErr)
+		assert.Equal(t, "parse", stageErr.Stage)
+		assert.Equal(t, "x", stageErr.Item)
+
+		err := p.Stop()
+		assert.ErrorIs(t, err, strconv.ErrSyntax)
+		assert.Equal(t, []int{1}, parsed)
+	})
+
+	t.Run("Errors handled by OnError", func(t *testing.T) {
+		var mu sync.Mutex
+		var failed []any
+
+		p := pool.NewPipeline(pool.PipelineConfig{
+			OnError: func(stage string, item any, err error) error {
+				mu.Lock()
+				failed = append(failed, item)
+				mu.Unlock()
+				return nil
+			},
+		}, pool.Stage{Name: "parse", Func: parseStage})
+		p.Run(context.Background())
+
+		for _, item := range []string{"1", "x", "2", "y"} {
+			require.NoError(t, p.Push(context.Background(), item))
+		}
+		require.NoError(t, p.Stop())
+
+		assert.ElementsMatch(t, []any{"x", "y"}, failed)
+		assert.Equal(t, uint64(2), p.Stats()["parse"].Failed)
+		assert.NoError(t, p.Err())
+	})
+
+	t.Run("Items rejected by a stage", func(t *testing.T) {
+		p := pool.NewPipeline(pool.PipelineConfig{},
+			pool.Stage{Name: "parse", Func: parseStage, Options: []pool.Option{pool.WithHeapLimit(1, pool.AdmissionReject)}},
+		)
+		p.Run(context.Background())
+		require.NoError(t, p.Push(context.Background(), "1"))
+
+		// The rejected item is not pending any more.
+		stopped := make(chan error)
+		go func() { stopped <- p.Stop() }()
+		select {
+		case err := <-stopped:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Stop waits for a rejected item")
+		}
+		assert.Equal(t, uint64(1), p.Stats()["parse"].Rejected)
+	})
+}
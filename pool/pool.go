@@ -3,7 +3,10 @@ package pool
 
 import (
 	"context"
+	"math"
+	"runtime"
 	"sync"
+	"time"
 )
 
 // Runner is an interface for a task that can be executed in worker pool.
@@ -11,42 +14,522 @@ type Runner interface {
 	Job(ctx context.Context)
 }
 
+// FallibleRunner is an interface for a task which reports a failure.
+// Use Fallible to execute it in a worker pool.
+type FallibleRunner interface {
+	Job(ctx context.Context) error
+}
+
+// Fallible adapts a FallibleRunner to the Runner interface.
+// The pool counts the returned errors as failed tasks.
+func Fallible(task FallibleRunner) Runner {
+	return fallible{task}
+}
+
+type fallible struct {
+	task FallibleRunner
+}
+
+func (f fallible) Job(ctx context.Context) {
+	_ = f.task.Job(ctx)
+}
+
+func (f fallible) unwrap() any {
+	return f.task
+}
+
 // Pool carries a worker tasks channel, a wait group, and other values.
 type Pool struct {
-	input      chan Runner
-	wg         sync.WaitGroup
-	workersCnt int
+	*core
+	ctx     context.Context
+	input   chan *job
+	ready   chan struct{}
+	drained chan struct{}
+	wg      sync.WaitGroup
+}
+
+// job is a task with the bookkeeping of the pool attached to it.
+type job struct {
+	task Runner
+	// ctx is the context the task was submitted with.
+	ctx context.Context
+	seq uint64
+	// done is called when the task is finished or skipped.
+	done   func()
+	handle *Handle
+}
+
+// cost returns the share of a scheduler turn the job takes.
+func (j *job) cost() int {
+	if w := weightOf(j.task, math.MaxInt64); w > 1 {
+		return int(w)
+	}
+
+	return 1
 }
 
 // New creates a new worker pool.
-func New(workersCnt int) *Pool {
-	return &Pool{
-		input:      make(chan Runner),
-		workersCnt: workersCnt,
+func New(workersCnt int, opts ...Option) *Pool {
+	p := &Pool{
+		core:  newCore(workersCnt, opts),
+		input: make(chan *job),
 	}
+
+	switch {
+	case p.cfg.deadline != nil:
+		p.sched = newDeadlineQueue(*p.cfg.deadline, p.cfg.clock, p.drop)
+	case p.cfg.tenants != nil:
+		p.sched = newFairQueue(*p.cfg.tenants)
+	}
+	if p.sched != nil {
+		p.ready = make(chan struct{})
+		p.drained = make(chan struct{})
+	}
+
+	return p
 }
 
 // Run starts workers in the pool.
+// With WithIdleTimeout, the workers are spawned later on demand.
 func (p *Pool) Run(ctx context.Context) {
-	for i := 0; i < p.workersCnt; i++ {
-		p.wg.Add(1)
+	p.ctx = ctx
+	p.phase.Store(phaseRunning)
+	if p.sched != nil {
+		go p.dispatch()
+	}
+	p.startWatchdog(p.replace)
+
+	p.fill()
+}
+
+// fill spawns the missing workers of a pool which is not lazy.
+func (p *Pool) fill() {
+	if p.lazy() {
+		return
+	}
+	for p.reserve(math.MaxInt64) {
+		p.spawn(p.ctx)
+	}
+}
+
+// spawn starts a worker which runs tasks until the pool is stopped or the worker is retired.
+// The worker is set up before spawn returns, so a retired worker is torn down only after
+// its successor is ready and the pool does not shrink.
+func (p *Pool) spawn(ctx context.Context) {
+	p.spawnWith(ctx, nil)
+}
+
+// spawnWith is spawn for a worker which runs first before taking tasks, unless first is nil.
+func (p *Pool) spawnWith(ctx context.Context, first *job) {
+	wctx, w := p.newWorker(ctx)
+	p.wg.Add(1)
+	p.idle.Add(1)
 
-		go func() {
-			for task := range p.input {
-				task.Job(ctx)
+	go func() {
+		defer func() {
+			// The watchdog has already let the pool go without an abandoned worker.
+			if !w.abandoned() {
+				p.wg.Done()
 			}
-			p.wg.Done()
 		}()
+		defer p.stopWorker(wctx, w)
+		p.pin()
+		p.mark(w)
+
+		for {
+			w.wakeup = p.nudged()
+			if p.dismiss() {
+				// The pool has been resized down.
+				p.idle.Add(-1)
+				return
+			}
+			// Paused and limited workers of the blocking pool still wait for their tasks, they stop
+			// when the pool is stopped.
+			_ = p.awaitResume(context.Background())
+			_ = p.enter(context.Background())
+			j, open := first, true
+			first = nil
+			if j == nil {
+				j, open = p.next(w)
+			}
+			if j == nil {
+				p.leave()
+				if w.nudged {
+					w.nudged = false
+					continue
+				}
+				if open && !p.lazy() {
+					// The worker has expired while waiting for a task.
+					p.spawn(ctx)
+					p.idle.Add(-1)
+					return
+				}
+
+				p.idle.Add(-1)
+				p.live.Add(-1)
+				if open {
+					// A task may have come while the worker was leaving.
+					p.grow()
+				}
+				return
+			}
+			p.idle.Add(-1)
+
+			w.busy()
+			p.run(wctx, j)
+			p.leave()
+			if !w.release() {
+				// A new worker has taken the place of this one while its task was stuck.
+				return
+			}
+
+			w.tasks++
+			if p.retired(w) {
+				p.spawn(ctx)
+				return
+			}
+			p.idle.Add(1)
+		}
+	}()
+}
+
+// next waits for the next task of a worker.
+// It returns nil if the pool is stopped or, with open set, if the worker has expired, been idle for too long
+// or been nudged to check the pool settings.
+func (p *Pool) next(w *worker) (j *job, open bool) {
+	nudged := w.wakeup
+
+	if p.sched != nil {
+		// Lets the scheduler choose a task at the moment the worker is free.
+		select {
+		case p.ready <- struct{}{}:
+			return p.receive()
+		default:
+		}
+		// No task is waiting for the worker.
+		p.rest()
+
+		idle, stop := p.idleTimer()
+		defer stop()
+
+		select {
+		case p.ready <- struct{}{}:
+		case <-p.drained:
+		case <-w.expiry:
+			return nil, true
+		case <-idle:
+			return nil, true
+		case <-nudged:
+			w.nudged = true
+			return nil, true
+		}
+
+		return p.receive()
+	}
+
+	select {
+	case j, open = <-p.input:
+		return j, open
+	default:
+	}
+	// No task is waiting for the worker.
+	p.rest()
+
+	idle, stop := p.idleTimer()
+	defer stop()
+
+	select {
+	case j, open = <-p.input:
+		return j, open
+	case <-p.stopped:
+		return nil, false
+	case <-w.expiry:
+		return nil, true
+	case <-idle:
+		return nil, true
+	case <-nudged:
+		w.nudged = true
+		return nil, true
+	}
+}
+
+// receive waits for the task of a worker which is ready (see dispatch).
+// It returns nil if the scheduler has been drained.
+func (p *Pool) receive() (*job, bool) {
+	select {
+	case j := <-p.input:
+		return j, true
+	case <-p.drained:
+		return nil, false
+	}
+}
+
+// Resize changes the number of workers of the pool. The extra workers exit when they finish
+// their current tasks.
+func (p *Pool) Resize(n int) error {
+	if err := p.resize(n); err != nil {
+		return err
+	}
+
+	if p.ctx != nil {
+		p.fill()
+		p.grow()
+	}
+	return nil
+}
+
+// grow spawns a worker if the pool is lazy (see WithIdleTimeout) and the waiting tasks outnumber the idle workers.
+func (p *Pool) grow() {
+	if !p.lazy() {
+		return
+	}
+
+	demand := p.waiting.Load()
+	if p.sched != nil {
+		demand += int64(p.sched.len())
+	}
+	if p.reserve(demand) {
+		p.spawn(p.ctx)
 	}
 }
 
 // Stop stops workers in the pool.
+// All tasks added before Stop are executed.
 func (p *Pool) Stop() {
-	close(p.input)
+	p.halt()
+	// The paused workers run the tasks added before Stop.
+	p.Resume()
+	if p.sched != nil {
+		p.sched.close()
+	}
+
 	p.wg.Wait()
+	p.stopWatchdog()
+}
+
+// replace starts a worker in place of one abandoned by the watchdog with a stuck task.
+func (p *Pool) replace() {
+	p.spawn(p.ctx)
+	p.wg.Done()
 }
 
 // Execute adds a new task in the tasks queue of a worker pool.
-func (p *Pool) Execute(task Runner) {
-	p.input <- task
+// It waits until a worker takes the task or, if the pool schedules tasks
+// (see WithTenants and WithDeadlineScheduling), until the task is queued.
+// The returned Handle reports the status of the task and cancels it.
+// While the pool is draining or after Stop the task is dropped without running: the handle
+// reports it failed with ErrPoolDraining or ErrPoolStopped and the task goes to the handler
+// set with WithRejectHandler. Use Submit or Start to get the error directly.
+func (p *Pool) Execute(task Runner) *Handle {
+	h, _ := p.Start(context.Background(), task)
+	return h
+}
+
+// Submit is Execute which gives up waiting when ctx is done.
+// It returns the context error or ErrPoolStopped if the task has not been added.
+// With WithDeadlineScheduling, the deadline of ctx defines the order of the queued tasks.
+func (p *Pool) Submit(ctx context.Context, task Runner) error {
+	_, err := p.Start(ctx, task)
+	return err
+}
+
+// Start is Submit which also returns the Handle of the task.
+// If the task has not been added, the handle reports it failed with the returned error.
+func (p *Pool) Start(ctx context.Context, task Runner) (*Handle, error) {
+	return p.start(ctx, task, nil)
+}
+
+// start is Start which calls onFinish when the task is finished or skipped.
+func (p *Pool) start(ctx context.Context, task Runner, onFinish func()) (*Handle, error) {
+	h := p.track(task, onFinish)
+	if err := p.submit(ctx, &job{task: task, ctx: ctx, handle: h}); err != nil {
+		h.finish(nil, err)
+		return h, err
+	}
+
+	return h, nil
+}
+
+// lend hands a task over to a free worker without waiting, onFinish is called when the task
+// is finished or skipped. A lazy pool spawns a worker for it if it may. It reports false
+// if no worker is free, the task is never queued.
+func (p *Pool) lend(task Runner, onFinish func()) bool {
+	if p.phase.Load() != phaseRunning || p.accepting() != nil {
+		return false
+	}
+
+	h := p.track(task, onFinish)
+	j := &job{task: task, ctx: context.Background(), handle: h}
+	if p.handOver(j) {
+		return true
+	}
+
+	if p.lazy() && p.reserve(1) {
+		p.spawnWith(p.ctx, j)
+		return true
+	}
+
+	p.untrack(h)
+	return false
+}
+
+const (
+	// handOverWait bounds the time handOver waits for an idle worker to get to its input.
+	handOverWait = 10 * time.Millisecond
+	// handOverPoll is the pause between the tries of handOver, the waiting worker may need
+	// the CPU of the caller.
+	handOverPoll = 50 * time.Microsecond
+)
+
+// handOver gives a job to a worker waiting for a task. A pool scheduling its tasks hands it
+// over only when no task is queued, the job does not overtake them.
+func (p *Pool) handOver(j *job) bool {
+	// The wait is measured with the system time, a worker is scheduled by the runtime.
+	start := time.Now()
+	for try := 0; ; try++ {
+		if p.sched == nil {
+			select {
+			case p.input <- j:
+				return true
+			default:
+			}
+		} else if p.sched.len() == 0 {
+			select {
+			case <-p.ready:
+				// The worker which is ready waits for this job.
+				select {
+				case p.input <- j:
+					return true
+				case <-p.drained:
+					return false
+				}
+			default:
+			}
+		}
+
+		if p.idle.Load() == 0 || time.Since(start) > handOverWait {
+			return false
+		}
+		// An idle worker may not have got to its input yet.
+		if try == 0 {
+			runtime.Gosched()
+		} else {
+			time.Sleep(handOverPoll)
+		}
+	}
+}
+
+// submit adds a job to the scheduler or hands it over to a free worker.
+func (p *Pool) submit(ctx context.Context, j *job) error {
+	if err := p.accepting(); err != nil {
+		p.reject(j.task, err)
+		return err
+	}
+
+	if p.sched != nil {
+		if err := p.sched.push(ctx, j); err != nil {
+			p.reject(j.task, err)
+			return err
+		}
+		p.grow()
+
+		return nil
+	}
+
+	if p.lazy() {
+		select {
+		case p.input <- j:
+			return nil
+		default:
+		}
+
+		// No worker is free, the pool spawns one if it may.
+		p.waiting.Add(1)
+		defer p.waiting.Add(-1)
+		p.grow()
+	}
+
+	select {
+	case p.input <- j:
+		return nil
+	case <-p.stopped:
+		p.reject(j.task, ErrPoolStopped)
+		return ErrPoolStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Tenants returns the statistic values of every tenant of the pool (see WithTenants).
+func (p *Pool) Tenants() map[string]TenantStats {
+	if q, ok := p.sched.(*fairQueue); ok {
+		return q.stats()
+	}
+
+	return nil
+}
+
+// dispatch hands the scheduled tasks over to free workers.
+func (p *Pool) dispatch() {
+	defer close(p.drained)
+
+	// A worker is taken only when there is a task for it, so idle workers may exit (see WithIdleTimeout).
+	free := false
+	for p.sched.wait() {
+		if !free {
+			<-p.ready
+			free = true
+		}
+
+		// The scheduler may skip all queued tasks, then the worker waits for the next one.
+		if j := p.sched.pop(); j != nil {
+			p.input <- j
+			free = false
+		}
+	}
+}
+
+// drop reports a scheduled task skipped without running.
+func (p *Pool) drop(j *job, err error) {
+	p.reject(j.task, err)
+	j.handle.finish(nil, err)
+	if j.done != nil {
+		j.done()
+	}
+}
+
+// run executes a task as soon as it fits into the pool budgets.
+// The task is skipped if it is rejected, cancelled or ctx is done before it is admitted.
+func (p *Pool) run(ctx context.Context, j *job) {
+	if j.done != nil {
+		defer j.done()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if !j.handle.bind(cancel) {
+		// The task has been cancelled while queued.
+		return
+	}
+
+	release, err := p.admit(ctx, j.task)
+	if err != nil {
+		j.handle.finish(nil, err)
+		return
+	}
+	defer release()
+
+	if !j.handle.start(ctx, nil) {
+		return
+	}
+	p.execute(ctx, j.task, func(ctx context.Context) error {
+		if f, ok := j.task.(fallible); ok {
+			err = f.task.Job(ctx)
+			return err
+		}
+
+		j.task.Job(ctx)
+		return nil
+	})
+	j.handle.finish(nil, err)
 }
@@ -2,14 +2,17 @@ package pool_test
 
 import (
 	"context"
-	"math/rand"
+	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
 )
 
 type counter struct {
@@ -27,28 +30,114 @@ func (a *add) Job(context.Context) {
 	a.cnt.value++
 	a.cnt.mu.Unlock()
 
-	time.Sleep(time.Duration(rand.Intn(10000)) * time.Microsecond)
 	a.wg.Done()
 }
 
 func TestPool_Run(t *testing.T) {
 	t.Run("Successful 99 tasks run", func(t *testing.T) {
+		pooltest.CheckLeaks(t)
 		var cnt counter
 		var wg sync.WaitGroup
+		sched := pooltest.NewScheduler()
 
-		workers := pool.New(10)
+		workers := pool.New(10, sched.Option())
 		workers.Run(context.Background())
 
+		wg.Add(99)
+		go func() {
+			for i := 0; i < 99; i++ {
+				workers.Execute(&add{
+					&cnt,
+					&wg,
+				})
+			}
+		}()
+
+		// The workers hold their tasks, so Execute waits for a step to free one of them.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 		for i := 0; i < 99; i++ {
-			wg.Add(1)
-			workers.Execute(&add{
-				&cnt,
-				&wg,
-			})
+			_, err := sched.Step(ctx)
+			require.NoError(t, err)
 		}
 		wg.Wait()
 		workers.Stop()
 
 		assert.Equal(t, 99, cnt.value)
 	})
+	t.Run("Submit gives up when ctx is done", func(t *testing.T) {
+		pooltest.CheckLeaks(t)
+		workers := pool.New(1)
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		require.NoError(t, workers.Submit(context.Background(), gate))
+		<-gate.started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := workers.Submit(ctx, gate)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(gate.release)
+		workers.Stop()
+	})
+	t.Run("Submit fails after Stop", func(t *testing.T) {
+		pooltest.CheckLeaks(t)
+		workers := pool.New(1)
+		workers.Run(context.Background())
+
+		gate := newBlocked()
+		require.NoError(t, workers.Submit(context.Background(), gate))
+		<-gate.started
+
+		// The caller waits for the busy worker when the pool is stopped.
+		waiting := make(chan error)
+		go func() { waiting <- workers.Submit(context.Background(), signal(make(chan struct{}))) }()
+		stopped := make(chan struct{})
+		go func() {
+			workers.Stop()
+			close(stopped)
+		}()
+
+		assert.ErrorIs(t, <-waiting, pool.ErrPoolStopped)
+		close(gate.release)
+		<-stopped
+
+		assert.ErrorIs(t, workers.Submit(context.Background(), gate), pool.ErrPoolStopped)
+	})
+}
+
+func TestPool_WithTaskHook(t *testing.T) {
+	var (
+		hooked []string
+		mu     sync.Mutex
+	)
+	hook := func(ctx context.Context, task any) func() {
+		_, ok := pool.WorkerID(ctx)
+		assert.True(t, ok)
+
+		mu.Lock()
+		hooked = append(hooked, fmt.Sprintf("before %T", task))
+		mu.Unlock()
+		return func() {
+			mu.Lock()
+			hooked = append(hooked, fmt.Sprintf("after %T", task))
+			mu.Unlock()
+		}
+	}
+
+	workers := pool.New(1, pool.WithTaskHook(hook))
+	workers.Run(context.Background())
+	h := workers.Execute(pool.Fallible(broken{errors.New("failed")}))
+	assert.Error(t, h.Wait(context.Background()))
+	workers.Stop()
+
+	nonBlocking := pool.NewNonBlocking[string](1, pool.WithTaskHook(func(context.Context, any) func() { return nil }))
+	nonBlocking.Run(context.Background())
+	assert.Equal(t, "a", nonBlocking.Submit(context.Background(), replica{name: "a"}).Value)
+	nonBlocking.Stop()
+
+	// The hook gets the task added to the pool, not the wrappers of the pool.
+	assert.Equal(t, []string{"before pool_test.broken", "after pool_test.broken"}, hooked)
 }
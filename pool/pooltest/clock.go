@@ -0,0 +1,201 @@
+// Package pooltest helps to test the code built on the worker pools without real sleeps:
+// a fake clock moved forward by hand, a scheduler running the tasks of a pool step by step
+// and a check for leaked goroutines.
+package pooltest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// Clock is a fake pool.Clock, its time moves only when Advance or Set is called.
+// Pass it to a pool with pool.WithClock.
+type Clock struct {
+	now    time.Time
+	timers []*timer
+	// changed is closed and replaced when a timer is started.
+	changed chan struct{}
+	mu      sync.Mutex
+}
+
+// NewClock returns a fake clock showing the time now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a timer which fires when the clock has been advanced by d.
+func (c *Clock) NewTimer(d time.Duration) pool.Timer {
+	t := &timer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+// NewTicker returns a ticker which ticks every time the clock has been advanced by d.
+// Like time.NewTicker, it panics if d is not positive.
+func (c *Clock) NewTicker(d time.Duration) pool.Ticker {
+	if d <= 0 {
+		panic("pooltest: non-positive interval for NewTicker")
+	}
+
+	t := &timer{clock: c, c: make(chan time.Time, 1), period: d}
+	t.Reset(d)
+
+	return ticker{t}
+}
+
+// Advance moves the clock forward by d and fires the timers due by then in the order of their times.
+// Like the channels of time.Timer, the channel of a timer keeps one value, a ticker drops the ticks
+// its reader has missed.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.advance(c.now.Add(d))
+}
+
+// Set moves the clock forward to the time now, a time in the past stops the clock until then.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.After(c.now) {
+		c.advance(now)
+	}
+}
+
+// Timers returns the number of the started timers and tickers of the clock.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// WaitTimers waits until the clock has at least n started timers and tickers, e.g. until
+// the code under test is ready for the clock to be advanced. It returns the context error
+// if ctx is done first.
+func (c *Clock) WaitTimers(ctx context.Context, n int) error {
+	for {
+		c.mu.Lock()
+		started, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if started >= n {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// advance fires the timers due by the time until, c.mu is held by the caller.
+func (c *Clock) advance(until time.Time) {
+	for len(c.timers) > 0 && !c.timers[0].when.After(until) {
+		t := c.timers[0]
+		c.now = t.when
+		t.fire()
+	}
+
+	c.now = until
+}
+
+// start adds a timer to the clock or moves it to its new time, c.mu is held by the caller.
+func (c *Clock) start(t *timer) {
+	c.remove(t)
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].when.Before(c.timers[j].when) })
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove takes a timer out of the clock, c.mu is held by the caller.
+// It reports whether the timer was started.
+func (c *Clock) remove(t *timer) bool {
+	for i, started := range c.timers {
+		if started == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// timer is a timer or, with a period, a ticker of a fake clock.
+type timer struct {
+	clock  *Clock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop stops the timer, it reports whether the timer was running.
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.remove(t)
+}
+
+// Reset makes the timer fire when the clock has been advanced by d, it reports whether the timer was running.
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	running := t.clock.remove(t)
+	t.when = t.clock.now.Add(d)
+	if d <= 0 && t.period == 0 {
+		t.fire()
+		return running
+	}
+
+	t.clock.start(t)
+	return running
+}
+
+// fire sends the time of the timer to its channel and starts a ticker for the next tick, c.mu is held by the caller.
+func (t *timer) fire() {
+	t.clock.remove(t)
+	select {
+	case t.c <- t.when:
+	default:
+	}
+
+	if t.period > 0 {
+		t.when = t.when.Add(t.period)
+		t.clock.start(t)
+	}
+}
+
+// ticker is the pool.Ticker of a periodic timer.
+type ticker struct {
+	*timer
+}
+
+func (t ticker) Stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,197 @@
+package pooltest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// waiting answers when its context is done.
+type waiting struct{}
+
+func (waiting) Job(ctx context.Context) pool.JobResponse[string] {
+	<-ctx.Done()
+	return pool.JobResponse[string]{Err: ctx.Err()}
+}
+
+// blocked runs until it is released.
+type blocked struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlocked() blocked {
+	return blocked{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (b blocked) Job(context.Context) {
+	close(b.started)
+	<-b.release
+}
+
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case now := <-c:
+		return now, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestClock(t *testing.T) {
+	t.Run("Timer", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		timer := clock.NewTimer(10 * time.Second)
+		assert.Equal(t, 1, clock.Timers())
+
+		clock.Advance(5 * time.Second)
+		_, ok := fired(timer.C())
+		assert.False(t, ok)
+
+		clock.Advance(10 * time.Second)
+		now, ok := fired(timer.C())
+		require.True(t, ok)
+		assert.Equal(t, epoch.Add(10*time.Second), now)
+		assert.Equal(t, epoch.Add(15*time.Second), clock.Now())
+		assert.Zero(t, clock.Timers())
+		assert.False(t, timer.Stop())
+
+		assert.False(t, timer.Reset(time.Second))
+		assert.True(t, timer.Stop())
+		clock.Advance(time.Minute)
+		_, ok = fired(timer.C())
+		assert.False(t, ok)
+	})
+
+	t.Run("Timers fire in order", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		late, early := clock.NewTimer(2*time.Second), clock.NewTimer(time.Second)
+
+		clock.Set(epoch.Add(time.Hour))
+		lateAt, ok := fired(late.C())
+		require.True(t, ok)
+		earlyAt, ok := fired(early.C())
+		require.True(t, ok)
+		assert.True(t, earlyAt.Before(lateAt))
+
+		// The clock does not go back.
+		clock.Set(epoch)
+		assert.Equal(t, epoch.Add(time.Hour), clock.Now())
+	})
+
+	t.Run("Ticker", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		ticker := clock.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		// The missed ticks are dropped.
+		clock.Advance(3500 * time.Millisecond)
+		now, ok := fired(ticker.C())
+		require.True(t, ok)
+		assert.Equal(t, epoch.Add(time.Second), now)
+		_, ok = fired(ticker.C())
+		assert.False(t, ok)
+
+		clock.Advance(500 * time.Millisecond)
+		now, ok = fired(ticker.C())
+		require.True(t, ok)
+		assert.Equal(t, epoch.Add(4*time.Second), now)
+
+		ticker.Stop()
+		clock.Advance(time.Minute)
+		_, ok = fired(ticker.C())
+		assert.False(t, ok)
+		assert.Panics(t, func() { clock.NewTicker(0) })
+	})
+
+	t.Run("Wait for timers", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		go clock.NewTimer(time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, clock.WaitTimers(ctx, 1))
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, clock.WaitTimers(ctx, 2), context.Canceled)
+	})
+}
+
+func TestClock_Pool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Idle workers exit", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		workers := pool.New(1, pool.WithClock(clock), pool.WithIdleTimeout(time.Minute))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		task := newBlocked()
+		h := workers.Execute(task)
+		close(task.release)
+		require.NoError(t, h.Wait(ctx))
+
+		// The idle worker waits for a task with its idle timer.
+		require.NoError(t, clock.WaitTimers(ctx, 1))
+		assert.Equal(t, 1, workers.Stats().Live)
+
+		clock.Advance(time.Minute)
+		require.Eventually(t, func() bool { return workers.Stats().Live == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Fan-out timeout", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		workers := pool.NewNonBlocking[string](2, pool.WithClock(clock))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		resps := make(chan []pool.JobResponse[string])
+		go func() {
+			resps <- workers.FanOut(context.Background(), pool.FanOutConfig{Timeout: time.Hour}, waiting{}, waiting{})
+		}()
+
+		require.NoError(t, clock.WaitTimers(ctx, 1))
+		clock.Advance(time.Hour)
+		for _, resp := range <-resps {
+			assert.ErrorIs(t, resp.Err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("Watchdog", func(t *testing.T) {
+		clock := pooltest.NewClock(epoch)
+		stuck := make(chan pool.TaskInfo, 1)
+		workers := pool.New(1, pool.WithClock(clock), pool.WithWatchdog(pool.WatchdogConfig{
+			Threshold: time.Minute,
+			Interval:  time.Minute,
+			OnStuck:   func(info pool.TaskInfo, _ []byte) { stuck <- info },
+		}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		task := newBlocked()
+		h := workers.Execute(task)
+		<-task.started
+		assert.Equal(t, epoch, h.Started())
+
+		// The watchdog checks the tasks with its ticker.
+		require.NoError(t, clock.WaitTimers(ctx, 1))
+		clock.Advance(time.Minute)
+		info := <-stuck
+		assert.Equal(t, h.ID(), info.ID)
+		assert.Equal(t, time.Minute, info.Elapsed)
+		close(task.release)
+	})
+}
@@ -0,0 +1,89 @@
+package pooltest
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// leakTimeout is the time the goroutines of a test have to exit after the test is over.
+	leakTimeout = 2 * time.Second
+	// leakPollInterval is the time between the checks of the running goroutines.
+	leakPollInterval = 10 * time.Millisecond
+)
+
+// CheckLeaks fails the test if goroutines started during the test are still running when the test
+// and its cleanups are over, e.g. the workers of a pool which has not been stopped.
+// Goroutines whose stack traces contain any of the ignore strings are not counted.
+// Call it at the start of the test. It does not tell the goroutines of the parallel tests apart.
+func CheckLeaks(t testing.TB, ignore ...string) {
+	t.Helper()
+
+	before := goroutines()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(leakTimeout)
+		for {
+			leaked := leakedSince(before, ignore)
+			if len(leaked) == 0 {
+				return
+			}
+
+			if time.Now().After(deadline) {
+				t.Errorf("leaked goroutines: %d\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+				return
+			}
+			time.Sleep(leakPollInterval)
+		}
+	})
+}
+
+// leakedSince returns the stack traces of the running goroutines which are not in before.
+func leakedSince(before map[string]string, ignore []string) []string {
+	var leaked []string
+	for id, stack := range goroutines() {
+		if _, ok := before[id]; ok || ignored(stack, ignore) {
+			continue
+		}
+
+		leaked = append(leaked, stack)
+	}
+	sort.Strings(leaked)
+
+	return leaked
+}
+
+func ignored(stack string, ignore []string) bool {
+	for _, s := range ignore {
+		if strings.Contains(stack, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goroutines returns the stack traces of the running goroutines except the current one by their IDs.
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[string]string)
+	// The first trace is of the current goroutine.
+	for _, stack := range bytes.Split(buf, []byte("\n\n"))[1:] {
+		header, _, _ := strings.Cut(string(stack), " [")
+		stacks[header] = string(stack)
+	}
+
+	return stacks
+}
@@ -0,0 +1,89 @@
+package pooltest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+// fakeT records the failures of a test and runs its cleanups on demand.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func parked(stop chan struct{}) {
+	<-stop
+}
+
+func TestCheckLeaks(t *testing.T) {
+	t.Run("Stopped pool", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		pooltest.CheckLeaks(ft)
+
+		workers := pool.New(4)
+		workers.Run(context.Background())
+		workers.Execute(logged{"a", &runLog{}})
+		workers.Stop()
+
+		ft.finish()
+		assert.Empty(t, ft.errors)
+	})
+
+	t.Run("Leaked goroutine", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		pooltest.CheckLeaks(ft)
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go parked(stop)
+
+		ft.finish()
+		if assert.Len(t, ft.errors, 1) {
+			assert.Contains(t, ft.errors[0], "leaked goroutines: 1")
+			assert.Contains(t, ft.errors[0], "pooltest_test.parked")
+		}
+	})
+
+	t.Run("Ignored goroutine", func(t *testing.T) {
+		ft := &fakeT{TB: t}
+		pooltest.CheckLeaks(ft, "pooltest_test.parked")
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go parked(stop)
+
+		ft.finish()
+		assert.Empty(t, ft.errors)
+	})
+
+	t.Run("Pool is stopped in a cleanup", func(t *testing.T) {
+		pooltest.CheckLeaks(t)
+
+		workers := pool.NewNonBlocking[string](4)
+		workers.Run(context.Background())
+		t.Cleanup(workers.Stop)
+	})
+}
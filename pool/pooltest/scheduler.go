@@ -0,0 +1,136 @@
+package pooltest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// Scheduler holds the tasks of a pool before they start and lets a test run them one by one,
+// so the order of the tasks does not depend on the Go scheduler. Pass Option to the pool.
+// The workers holding the tasks are busy, so the pool takes as many tasks as it has workers.
+type Scheduler struct {
+	held     []*held
+	released bool
+	// changed is closed and replaced when a task is held.
+	changed chan struct{}
+	mu      sync.Mutex
+}
+
+// held is a task waiting for its step.
+type held struct {
+	task any
+	run  chan struct{}
+	done chan struct{}
+}
+
+// NewScheduler returns a scheduler holding all tasks until they are stepped or released.
+func NewScheduler() *Scheduler {
+	return &Scheduler{changed: make(chan struct{})}
+}
+
+// Option makes a pool hand every task over to the scheduler before the task starts.
+func (s *Scheduler) Option() pool.Option {
+	return pool.WithTaskHook(s.hold)
+}
+
+// Held returns the held tasks in the order the workers took them.
+func (s *Scheduler) Held() []any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]any, len(s.held))
+	for i, h := range s.held {
+		tasks[i] = h.task
+	}
+
+	return tasks
+}
+
+// Wait waits until the scheduler holds at least n tasks or ctx is done.
+func (s *Scheduler) Wait(ctx context.Context, n int) error {
+	for {
+		s.mu.Lock()
+		count, changed := len(s.held), s.changed
+		s.mu.Unlock()
+		if count >= n {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Step runs the task held longest and waits until it finishes. If no task is held,
+// it waits for one. It returns the task which has run or the context error.
+func (s *Scheduler) Step(ctx context.Context) (any, error) {
+	if err := s.Wait(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	h := s.held[0]
+	s.held = s.held[1:]
+	s.mu.Unlock()
+
+	close(h.run)
+	select {
+	case <-h.done:
+		return h.task, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release runs the held tasks and lets the later ones start without waiting, e.g. before
+// the pool is stopped.
+func (s *Scheduler) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.released = true
+	for _, h := range s.held {
+		close(h.run)
+	}
+	s.held = nil
+}
+
+// hold is the task hook of the pool, it keeps the task until it is stepped or its context is done.
+func (s *Scheduler) hold(ctx context.Context, task any) func() {
+	s.mu.Lock()
+	if s.released {
+		s.mu.Unlock()
+		return nil
+	}
+
+	h := &held{
+		task: task,
+		run:  make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.held = append(s.held, h)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+
+	select {
+	case <-h.run:
+	case <-ctx.Done():
+		// The task starts with its context done unless a step has already taken it.
+		s.mu.Lock()
+		for i, other := range s.held {
+			if other == h {
+				s.held = append(s.held[:i], s.held[i+1:]...)
+				break
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	return func() { close(h.done) }
+}
@@ -0,0 +1,116 @@
+package pooltest_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+// logged appends its name to the log when it runs.
+type logged struct {
+	name string
+	log  *runLog
+}
+
+func (l logged) Job(context.Context) {
+	l.log.add(l.name)
+}
+
+type runLog struct {
+	names []string
+	mu    sync.Mutex
+}
+
+func (l *runLog) add(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.names = append(l.names, name)
+}
+
+func (l *runLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]string(nil), l.names...)
+}
+
+func TestScheduler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("Step", func(t *testing.T) {
+		sched := pooltest.NewScheduler()
+		workers := pool.New(2, sched.Option())
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		var log runLog
+		go func() {
+			for _, name := range []string{"a", "b", "c"} {
+				workers.Execute(logged{name, &log})
+			}
+		}()
+
+		// Both workers hold a task, the third one waits for a worker.
+		require.NoError(t, sched.Wait(ctx, 2))
+		assert.Len(t, sched.Held(), 2)
+		assert.Empty(t, log.get())
+
+		var stepped []string
+		for i := 0; i < 3; i++ {
+			task, err := sched.Step(ctx)
+			require.NoError(t, err)
+			stepped = append(stepped, task.(logged).name)
+			assert.Equal(t, stepped, log.get())
+		}
+		assert.ElementsMatch(t, []string{"a", "b", "c"}, stepped)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		sched := pooltest.NewScheduler()
+		workers := pool.New(1, sched.Option())
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		var log runLog
+		h := workers.Execute(logged{"a", &log})
+		require.NoError(t, sched.Wait(ctx, 1))
+
+		sched.Release()
+		require.NoError(t, h.Wait(ctx))
+		require.NoError(t, workers.Execute(logged{"b", &log}).Wait(ctx))
+		assert.Equal(t, []string{"a", "b"}, log.get())
+		assert.Empty(t, sched.Held())
+	})
+
+	t.Run("Held task of a stopped pool", func(t *testing.T) {
+		sched := pooltest.NewScheduler()
+		workers := pool.NewNonBlocking[string](1, sched.Option())
+		workers.Run(context.Background())
+
+		h := workers.Start(context.Background(), waiting{})
+		require.NoError(t, sched.Wait(ctx, 1))
+
+		// The task is let go with its context done.
+		workers.Stop()
+		assert.ErrorIs(t, h.Err(), context.Canceled)
+		assert.Empty(t, sched.Held())
+	})
+
+	t.Run("Step waits for a task", func(t *testing.T) {
+		sched := pooltest.NewScheduler()
+
+		stepCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := sched.Step(stepCtx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,301 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOverloaded  = fmt.Errorf("pool overloaded")
+	ErrPoolStopped = fmt.Errorf("pool stopped")
+)
+
+// OverloadError is returned when the pool sheds a task because of overload.
+// It matches ErrOverloaded with errors.Is.
+type OverloadError struct {
+	// RetryAfter is an estimate of the time after which the pool is able to accept the task.
+	RetryAfter time.Duration
+}
+
+func (e *OverloadError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", ErrOverloaded, e.RetryAfter)
+}
+
+func (e *OverloadError) Unwrap() error {
+	return ErrOverloaded
+}
+
+// QueueConfig keeps the settings of the queue of callers waiting for a worker.
+type QueueConfig struct {
+	// Target is the acceptable time a caller spends in the queue.
+	// When the shortest waiting time stays above Target for a whole Interval,
+	// the queue is overloaded and sheds callers waiting longer than Target.
+	// Zero means the queue never sheds because of the waiting time.
+	Target time.Duration
+	// Interval is the period over which the waiting times are measured. Default is 100ms.
+	Interval time.Duration
+	// MaxLen bounds the number of waiting callers, 0 means no bound.
+	MaxLen int
+	// LIFO makes an overloaded queue serve the newest callers first, they still have time
+	// to get a response, instead of shedding new callers immediately.
+	LIFO bool
+}
+
+// entry is a caller waiting in the queue.
+type entry struct {
+	ctx context.Context
+	// task is the task the caller is going to execute if it is known.
+	task     any
+	enqueued time.Time
+	// grant receives exactly one value when the entry leaves the queue:
+	// either what the caller waited for or an error.
+	grant chan any
+	elem  *list.Element
+}
+
+// queue keeps the waiting callers and sheds them using the CoDel algorithm.
+type queue struct {
+	cfg        QueueConfig
+	entries    list.List
+	changed    chan struct{}
+	closed     bool
+	edf        bool
+	clock      Clock
+	retryAfter func(queued int) time.Duration
+
+	// CoDel state: the shortest waiting time in the current interval.
+	intervalStart time.Time
+	minDelay      time.Duration
+	overloaded    bool
+
+	mu sync.Mutex
+}
+
+func newQueue(cfg QueueConfig, edf bool, clock Clock, retryAfter func(queued int) time.Duration) *queue {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 100 * time.Millisecond
+	}
+
+	return &queue{
+		cfg:        cfg,
+		changed:    make(chan struct{}),
+		edf:        edf,
+		clock:      clock,
+		retryAfter: retryAfter,
+	}
+}
+
+// push adds a new waiting caller to the queue.
+// It fails with an OverloadError if the caller has to be shed immediately.
+func (q *queue) push(ctx context.Context, task any) (*entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrPoolStopped
+	}
+
+	if q.entries.Len() > 0 {
+		// While all workers are busy nobody pops, the queue is measured by the oldest caller.
+		now := q.clock.Now()
+		delay := now.Sub(q.entries.Front().Value.(*entry).enqueued)
+		q.observe(now, delay)
+		if q.cfg.Target > 0 && delay-q.cfg.Target >= q.cfg.Interval {
+			// The oldest caller has stayed above Target for a whole interval.
+			q.overloaded = true
+		}
+	}
+
+	full := q.cfg.MaxLen > 0 && q.entries.Len() >= q.cfg.MaxLen
+	shedding := q.overloaded && q.entries.Len() > 0
+
+	switch {
+	case q.cfg.LIFO && shedding && full:
+		// Makes room for the newest caller at the expense of the oldest one.
+		q.drop(q.entries.Front().Value.(*entry), q.overloadError())
+
+	case full, shedding && !q.cfg.LIFO:
+		return nil, q.overloadError()
+	}
+
+	e := &entry{
+		ctx:      ctx,
+		task:     task,
+		enqueued: q.clock.Now(),
+		grant:    make(chan any, 1),
+	}
+	e.elem = q.entries.PushBack(e)
+	q.notify()
+
+	return e, nil
+}
+
+// remove takes a caller who gave up out of the queue.
+// It returns false if the entry has already left the queue and got its grant.
+func (q *queue) remove(e *entry) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if e.elem == nil {
+		return false
+	}
+
+	q.entries.Remove(e.elem)
+	e.elem = nil
+
+	return true
+}
+
+// pop takes the next caller to be served out of the queue.
+// Callers waiting for too long in an overloaded queue are shed.
+// It returns nil if the queue is empty.
+func (q *queue) pop() *entry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.entries.Len() == 0 {
+		return nil
+	}
+
+	now := q.clock.Now()
+	q.observe(now, now.Sub(q.entries.Front().Value.(*entry).enqueued))
+
+	for q.overloaded && q.entries.Len() > 0 {
+		oldest := q.entries.Front().Value.(*entry)
+		if now.Sub(oldest.enqueued) <= q.cfg.Target {
+			break
+		}
+		q.drop(oldest, q.overloadError())
+	}
+
+	if q.edf {
+		q.dropLate(now)
+	}
+
+	var e *entry
+	if next := q.entries.Front(); next != nil {
+		switch {
+		case q.edf:
+			next = q.earliest()
+		case q.overloaded && q.cfg.LIFO:
+			next = q.entries.Back()
+		}
+
+		e = next.Value.(*entry)
+		q.entries.Remove(next)
+		e.elem = nil
+	}
+
+	if q.entries.Len() == 0 {
+		// The queue has drained, so it is not standing in this interval.
+		q.minDelay = 0
+	}
+
+	return e
+}
+
+// wait blocks until the queue has a waiting caller or ctx is done.
+func (q *queue) wait(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		if q.entries.Len() > 0 {
+			q.mu.Unlock()
+			return nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// close sheds all waiting callers with err and rejects new ones.
+func (q *queue) close(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	for q.entries.Len() > 0 {
+		q.drop(q.entries.Front().Value.(*entry), err)
+	}
+}
+
+// len returns the number of waiting callers.
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.entries.Len()
+}
+
+// observe updates the CoDel state with the waiting time of the oldest caller.
+func (q *queue) observe(now time.Time, delay time.Duration) {
+	if q.intervalStart.IsZero() {
+		q.intervalStart = now
+		q.minDelay = delay
+		return
+	}
+
+	if delay < q.minDelay {
+		q.minDelay = delay
+	}
+
+	if now.Sub(q.intervalStart) >= q.cfg.Interval {
+		q.overloaded = q.cfg.Target > 0 && q.minDelay > q.cfg.Target
+		q.intervalStart = now
+		q.minDelay = delay
+	}
+}
+
+// dropLate sheds the callers who cannot get their task done before the deadline.
+func (q *queue) dropLate(now time.Time) {
+	for elem := q.entries.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry)
+		if err := lateness(e.ctx, e.task, now); err != nil {
+			q.drop(e, err)
+		}
+		elem = next
+	}
+}
+
+// earliest returns the caller with the earliest deadline.
+func (q *queue) earliest() *list.Element {
+	first := q.entries.Front()
+	for elem := first.Next(); elem != nil; elem = elem.Next() {
+		if earlier(elem.Value.(*entry).ctx, first.Value.(*entry).ctx) {
+			first = elem
+		}
+	}
+
+	return first
+}
+
+func (q *queue) drop(e *entry, err error) {
+	q.entries.Remove(e.elem)
+	e.elem = nil
+	e.grant <- err
+}
+
+func (q *queue) overloadError() error {
+	retry := q.cfg.Interval
+	if q.retryAfter != nil {
+		if d := q.retryAfter(q.entries.Len()); d > retry {
+			retry = d
+		}
+	}
+
+	return &OverloadError{RetryAfter: retry}
+}
+
+func (q *queue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
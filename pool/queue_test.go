@@ -0,0 +1,238 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+	"github.com/illyasch/worker-pool/pool/pooltest"
+)
+
+type sleep struct {
+	d time.Duration
+}
+
+func (s sleep) Job(context.Context) pool.JobResponse[string] {
+	time.Sleep(s.d)
+	return pool.JobResponse[string]{Value: s.d.String()}
+}
+
+func TestNonBlocking_Submit(t *testing.T) {
+	t.Run("Without queue", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](2)
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		resp := workers.Submit(context.Background(), sleep{time.Millisecond})
+		require.NoError(t, resp.Err)
+		assert.Equal(t, "1ms", resp.Value)
+	})
+
+	t.Run("With queue", func(t *testing.T) {
+		const total = 20
+
+		workers := pool.NewNonBlocking[string](3, pool.WithQueue(pool.QueueConfig{Target: time.Second}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		var wg sync.WaitGroup
+		for i := 0; i < total; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp := workers.Submit(context.Background(), sleep{time.Millisecond})
+				assert.NoError(t, resp.Err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, uint64(total), workers.Stats().Completed)
+	})
+
+	t.Run("Expired context", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1, pool.WithQueue(pool.QueueConfig{Target: time.Second}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		resp := workers.Submit(ctx, sleep{time.Millisecond})
+		assert.ErrorIs(t, resp.Err, context.Canceled)
+	})
+}
+
+func TestNonBlocking_Acquire(t *testing.T) {
+	t.Run("Full queue sheds immediately", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1, pool.WithQueue(pool.QueueConfig{
+			Target: time.Second,
+			MaxLen: 1,
+		}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		waiting := make(chan error)
+		go func() {
+			req, err := workers.Acquire(context.Background())
+			if err == nil {
+				req.Close()
+			}
+			waiting <- err
+		}()
+		require.Eventually(t, func() bool { return workers.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		_, err = workers.Acquire(context.Background())
+		var overload *pool.OverloadError
+		require.ErrorAs(t, err, &overload)
+		assert.ErrorIs(t, err, pool.ErrOverloaded)
+		assert.Greater(t, overload.RetryAfter, time.Duration(0))
+
+		busy.Close()
+		assert.NoError(t, <-waiting)
+	})
+
+	t.Run("Cancelled caller leaves the queue", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1, pool.WithQueue(pool.QueueConfig{Target: time.Second}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = workers.Acquire(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 0, workers.Stats().Queued)
+
+		busy.Close()
+		resp := workers.Submit(context.Background(), sleep{time.Millisecond})
+		assert.NoError(t, resp.Err)
+	})
+
+	t.Run("Standing queue is shed", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1, pool.WithQueue(pool.QueueConfig{
+			Target:   time.Millisecond,
+			Interval: 5 * time.Millisecond,
+		}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		overloaded := 0
+
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp := workers.Submit(context.Background(), sleep{5 * time.Millisecond})
+				if errors.Is(resp.Err, pool.ErrOverloaded) {
+					mu.Lock()
+					overloaded++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Greater(t, overloaded, 0)
+		assert.Equal(t, uint64(overloaded), workers.Stats().Rejected)
+	})
+
+	t.Run("Standing queue behind busy workers is shed", func(t *testing.T) {
+		clock := pooltest.NewClock(time.Now())
+		workers := pool.NewNonBlocking[string](1, pool.WithClock(clock), pool.WithQueue(pool.QueueConfig{
+			Target:   5 * time.Millisecond,
+			Interval: 100 * time.Millisecond,
+		}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		waiting := make(chan error)
+		go func() {
+			req, err := workers.Acquire(context.Background())
+			if err == nil {
+				req.Close()
+			}
+			waiting <- err
+		}()
+		require.Eventually(t, func() bool { return workers.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		// Nobody has left the queue, the waiting caller has been above Target for longer than Interval.
+		clock.Advance(500 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = workers.Acquire(ctx)
+		assert.ErrorIs(t, err, pool.ErrOverloaded)
+		assert.NoError(t, ctx.Err())
+
+		// The free worker does not serve the caller who has waited too long either.
+		busy.Close()
+		assert.ErrorIs(t, <-waiting, pool.ErrOverloaded)
+	})
+
+	t.Run("Stop fails waiting callers without queue", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1)
+		workers.Run(context.Background())
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		waiting := make(chan error)
+		go func() {
+			_, err := workers.Acquire(context.Background())
+			waiting <- err
+		}()
+		stopped := make(chan struct{})
+		go func() {
+			workers.Stop()
+			close(stopped)
+		}()
+
+		assert.ErrorIs(t, <-waiting, pool.ErrPoolStopped)
+		busy.Close()
+		<-stopped
+
+		resp := workers.Submit(context.Background(), sleep{time.Millisecond})
+		assert.ErrorIs(t, resp.Err, pool.ErrPoolStopped)
+	})
+
+	t.Run("Stop sheds waiting callers", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](1, pool.WithQueue(pool.QueueConfig{Target: time.Second}))
+		workers.Run(context.Background())
+
+		busy, err := workers.Acquire(context.Background())
+		require.NoError(t, err)
+
+		waiting := make(chan error)
+		go func() {
+			_, err := workers.Acquire(context.Background())
+			waiting <- err
+		}()
+		require.Eventually(t, func() bool { return workers.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		stopped := make(chan struct{})
+		go func() {
+			workers.Stop()
+			close(stopped)
+		}()
+
+		assert.ErrorIs(t, <-waiting, pool.ErrPoolStopped)
+		busy.Close()
+		<-stopped
+	})
+}
@@ -0,0 +1,231 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventKind is the kind of a recorded event of a task.
+type EventKind int
+
+const (
+	// EventEnqueue is recorded when a task is added to a pool.
+	EventEnqueue EventKind = iota
+	// EventStart is recorded when a worker starts a task.
+	EventStart
+	// EventFinish is recorded when a task is finished, cancelled or rejected.
+	EventFinish
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventEnqueue:
+		return "enqueue"
+	case EventStart:
+		return "start"
+	case EventFinish:
+		return "finish"
+	}
+
+	return "unknown"
+}
+
+// Event is an event of a task recorded by a Recorder.
+type Event struct {
+	Kind EventKind
+	// Pool is the name of the pool (see WithName).
+	Pool string
+	// Task is the ID of the task (see Handle).
+	Task uint64
+	// Type is the type of the task.
+	Type string
+	// Worker is the ID of the worker running the task, 0 for a task which has not started.
+	Worker int
+	// Status is the status of the task after the event.
+	Status TaskStatus
+	Time   time.Time
+	// source tells apart the pools with the same name.
+	source *core
+}
+
+// Recorder keeps the latest events of the tasks of the pools in a ring buffer (see WithRecorder).
+type Recorder struct {
+	events []Event
+	next   int
+	full   bool
+	mu     sync.Mutex
+}
+
+// NewRecorder creates a new Recorder keeping the latest size events. Default size is 10000.
+func NewRecorder(size int) *Recorder {
+	if size < 1 {
+		size = 10000
+	}
+
+	return &Recorder{events: make([]Event, size)}
+}
+
+// Events returns the recorded events, the oldest first.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Event(nil), r.events[:r.next]...)
+	}
+
+	return append(append([]Event(nil), r.events[r.next:]...), r.events[:r.next]...)
+}
+
+// add adds an event to the buffer, replacing the oldest one when it is full.
+func (r *Recorder) add(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[r.next] = e
+	r.next++
+	if r.next == len(r.events) {
+		r.next, r.full = 0, true
+	}
+}
+
+// record records an event of a task if the pool has a recorder, the handle is locked by the caller.
+func (c *core) record(h *Handle, kind EventKind, t time.Time) {
+	if c.cfg.recorder == nil {
+		return
+	}
+
+	c.cfg.recorder.add(Event{
+		Kind:   kind,
+		Pool:   c.cfg.name,
+		Task:   h.id,
+		Type:   taskType(h.task),
+		Worker: h.worker,
+		Status: h.status,
+		Time:   t,
+		source: c,
+	})
+}
+
+// traceEvent is an event of the Chrome trace event format.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Phase string         `json:"ph"`
+	Time  float64        `json:"ts"`
+	Dur   float64        `json:"dur,omitempty"`
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the recorded events in the Chrome trace event format, which Perfetto
+// and chrome://tracing show. Every pool is a process and every worker is a thread of it;
+// a task is a slice of its worker, an added task is an instant event of the "queue" thread.
+// The tasks still running are shown up to the moment of the export.
+func (r *Recorder) WriteChromeTrace(w io.Writer) error {
+	events := r.Events()
+	t := chromeTrace{
+		events:  []traceEvent{},
+		pids:    make(map[*core]int),
+		threads: make(map[[2]int]bool),
+	}
+	if len(events) > 0 {
+		t.origin = events[0].Time
+	}
+
+	type taskKey struct {
+		source *core
+		task   uint64
+	}
+	started := make(map[taskKey]Event)
+	for _, e := range events {
+		key := taskKey{e.source, e.Task}
+
+		switch e.Kind {
+		case EventEnqueue:
+			pid := t.pid(e)
+			t.thread(pid, 0)
+			t.events = append(t.events, traceEvent{Name: e.Type, Phase: "i", Time: t.ts(e.Time), Pid: pid, Scope: "t",
+				Args: map[string]any{"task": e.Task}})
+		case EventStart:
+			started[key] = e
+		case EventFinish:
+			// A task which has not run or whose start has left the buffer is skipped.
+			if s, ok := started[key]; ok {
+				delete(started, key)
+				t.slice(s, e.Time, e.Status)
+			}
+		}
+	}
+
+	for _, s := range started {
+		t.slice(s, s.source.cfg.clock.Now(), TaskRunning)
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{
+		TraceEvents:     t.events,
+		DisplayTimeUnit: "ms",
+	})
+}
+
+// chromeTrace builds a trace in the Chrome trace event format.
+type chromeTrace struct {
+	origin  time.Time
+	events  []traceEvent
+	pids    map[*core]int
+	threads map[[2]int]bool
+}
+
+// ts returns the timestamp of a moment in microseconds since the first event.
+func (t *chromeTrace) ts(at time.Time) float64 {
+	return float64(at.Sub(t.origin)) / float64(time.Microsecond)
+}
+
+// pid returns the process ID of the pool of an event, naming the process on its first event.
+func (t *chromeTrace) pid(e Event) int {
+	if pid, ok := t.pids[e.source]; ok {
+		return pid
+	}
+
+	pid := len(t.pids) + 1
+	t.pids[e.source] = pid
+	name := e.Pool
+	if name == "" {
+		name = fmt.Sprintf("pool %d", pid)
+	}
+	t.events = append(t.events, traceEvent{Name: "process_name", Phase: "M", Pid: pid,
+		Args: map[string]any{"name": name}})
+
+	return pid
+}
+
+// thread names a thread on its first event.
+func (t *chromeTrace) thread(pid, tid int) {
+	if t.threads[[2]int{pid, tid}] {
+		return
+	}
+	t.threads[[2]int{pid, tid}] = true
+
+	name := fmt.Sprintf("worker %d", tid)
+	if tid == 0 {
+		name = "queue"
+	}
+	t.events = append(t.events, traceEvent{Name: "thread_name", Phase: "M", Pid: pid, Tid: tid,
+		Args: map[string]any{"name": name}})
+}
+
+// slice adds a task run by a worker from its start to the end.
+func (t *chromeTrace) slice(start Event, end time.Time, status TaskStatus) {
+	pid := t.pid(start)
+	t.thread(pid, start.Worker)
+	t.events = append(t.events, traceEvent{Name: start.Type, Phase: "X", Time: t.ts(start.Time),
+		Dur: t.ts(end) - t.ts(start.Time), Pid: pid, Tid: start.Worker,
+		Args: map[string]any{"task": start.Task, "status": status.String()}})
+}
@@ -0,0 +1,119 @@
+package pool_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+type chromeTrace struct {
+	TraceEvents []struct {
+		Name  string         `json:"name"`
+		Phase string         `json:"ph"`
+		Time  float64        `json:"ts"`
+		Dur   float64        `json:"dur"`
+		Pid   int            `json:"pid"`
+		Tid   int            `json:"tid"`
+		Args  map[string]any `json:"args"`
+	} `json:"traceEvents"`
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("Events", func(t *testing.T) {
+		rec := pool.NewRecorder(0)
+		workers := pool.New(1, pool.WithName("recorded"), pool.WithRecorder(rec))
+		workers.Run(context.Background())
+
+		h := workers.Execute(signal(make(chan struct{})))
+		require.NoError(t, h.Wait(context.Background()))
+		workers.Stop()
+
+		events := rec.Events()
+		require.Len(t, events, 3)
+		for i, kind := range []pool.EventKind{pool.EventEnqueue, pool.EventStart, pool.EventFinish} {
+			assert.Equal(t, kind, events[i].Kind)
+			assert.Equal(t, "recorded", events[i].Pool)
+			assert.Equal(t, h.ID(), events[i].Task)
+			assert.Equal(t, "pool_test.signal", events[i].Type)
+		}
+		assert.Zero(t, events[0].Worker)
+		assert.Equal(t, 1, events[1].Worker)
+		assert.Equal(t, pool.TaskDone, events[2].Status)
+		assert.False(t, events[2].Time.Before(events[1].Time))
+	})
+
+	t.Run("Ring buffer keeps the latest events", func(t *testing.T) {
+		rec := pool.NewRecorder(4)
+		workers := pool.New(1, pool.WithRecorder(rec))
+		workers.Run(context.Background())
+
+		var last *pool.Handle
+		for i := 0; i < 3; i++ {
+			last = workers.Execute(signal(make(chan struct{})))
+			require.NoError(t, last.Wait(context.Background()))
+		}
+		workers.Stop()
+
+		events := rec.Events()
+		require.Len(t, events, 4)
+		assert.Equal(t, pool.EventFinish, events[3].Kind)
+		assert.Equal(t, last.ID(), events[3].Task)
+	})
+
+	t.Run("Chrome trace", func(t *testing.T) {
+		rec := pool.NewRecorder(0)
+		first := pool.New(2, pool.WithName("first"), pool.WithRecorder(rec))
+		second := pool.NewNonBlocking[string](1, pool.WithRecorder(rec))
+		first.Run(context.Background())
+		second.Run(context.Background())
+		defer second.Stop()
+
+		require.NoError(t, first.Execute(signal(make(chan struct{}))).Wait(context.Background()))
+		require.NoError(t, second.Submit(context.Background(), replica{name: "a"}).Err)
+		gate := newBlocked()
+		first.Execute(gate)
+		<-gate.started
+
+		var buf bytes.Buffer
+		require.NoError(t, rec.WriteChromeTrace(&buf))
+		close(gate.release)
+		first.Stop()
+
+		var trace chromeTrace
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+
+		processes := make(map[int]string)
+		slices := make(map[string]string)
+		for _, e := range trace.TraceEvents {
+			switch e.Phase {
+			case "M":
+				if e.Name == "process_name" {
+					processes[e.Pid] = e.Args["name"].(string)
+				}
+			case "X":
+				assert.NotZero(t, e.Tid)
+				assert.GreaterOrEqual(t, e.Dur, float64(0))
+				slices[e.Name] = e.Args["status"].(string)
+			}
+		}
+
+		assert.Equal(t, map[int]string{1: "first", 2: "pool 2"}, processes)
+		assert.Equal(t, map[string]string{
+			"pool_test.signal":  "done",
+			"pool_test.replica": "done",
+			"pool_test.blocked": "running",
+		}, slices)
+	})
+
+	t.Run("Empty trace", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, pool.NewRecorder(1).WriteChromeTrace(&buf))
+		assert.JSONEq(t, `{"traceEvents":[],"displayTimeUnit":"ms"}`, buf.String())
+	})
+}
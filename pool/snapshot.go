@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"sort"
+	"time"
+)
+
+// Describer is an optional interface for a task which describes itself in a Snapshot,
+// e.g. with the request it serves.
+type Describer interface {
+	Describe() string
+}
+
+// TaskInfo describes a task.
+type TaskInfo struct {
+	// ID is the ID of the task (see Task).
+	ID uint64
+	// Status is the status of the task, TaskQueued or TaskRunning in a Snapshot.
+	Status TaskStatus
+	// Worker is the ID of the worker running the task, 0 for a queued task.
+	Worker int
+	// Queued is the time the task was added to the pool.
+	Queued time.Time
+	// Started is the time a worker started the task, zero for a queued task.
+	Started time.Time
+	// Elapsed is the time the task has been running, or waiting for a worker if it is queued.
+	// For a finished task it is the time it ran, or waited if it did not start.
+	Elapsed time.Duration
+	// Description is the description of a task implementing Describer.
+	Description string
+}
+
+// Snapshot lists the tasks of a pool at a moment.
+type Snapshot struct {
+	// Taken is the time the snapshot was taken.
+	Taken time.Time
+	// Running are the running tasks, the longest running first.
+	Running []TaskInfo
+	// Queued are the tasks waiting for a worker in the order they were added.
+	Queued []TaskInfo
+}
+
+// Snapshot returns the running and queued tasks of the pool.
+func (c *core) Snapshot() Snapshot {
+	s := Snapshot{Taken: c.cfg.clock.Now()}
+	for _, h := range c.Tasks() {
+		info := h.info(s.Taken)
+		switch info.Status {
+		case TaskRunning:
+			s.Running = append(s.Running, info)
+		case TaskQueued:
+			s.Queued = append(s.Queued, info)
+		}
+	}
+
+	// The tasks are ordered by ID, so by the time they were added, not started.
+	sort.SliceStable(s.Running, func(i, j int) bool { return s.Running[i].Started.Before(s.Running[j].Started) })
+
+	return s
+}
+
+// info describes the task at the time now.
+func (h *Handle) info(now time.Time) TaskInfo {
+	h.mu.Lock()
+	if h.status > TaskRunning {
+		now = h.finished
+	}
+	info := TaskInfo{
+		ID:      h.id,
+		Status:  h.status,
+		Worker:  h.worker,
+		Queued:  h.queued,
+		Started: h.started,
+	}
+	h.mu.Unlock()
+
+	if !info.Started.IsZero() {
+		info.Elapsed = now.Sub(info.Started)
+	} else {
+		info.Elapsed = now.Sub(info.Queued)
+	}
+	if d, ok := taskAs[Describer](h.task); ok {
+		info.Description = d.Describe()
+	}
+
+	return info
+}
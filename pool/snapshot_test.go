@@ -0,0 +1,81 @@
+package pool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// described is a blocked task describing itself.
+type described struct {
+	blocked
+	name string
+}
+
+func (d described) Describe() string {
+	return "task " + d.name
+}
+
+func TestPool_Snapshot(t *testing.T) {
+	workers := pool.New(2, pool.WithTenants(pool.TenantConfig{}))
+	workers.Run(context.Background())
+
+	a, b := described{newBlocked(), "a"}, newBlocked()
+	ha := workers.Execute(a)
+	<-a.started
+	hb := workers.Execute(b)
+	<-b.started
+	hc := workers.Execute(described{newBlocked(), "c"})
+
+	s := workers.Snapshot()
+	require.Len(t, s.Running, 2)
+	require.Len(t, s.Queued, 1)
+
+	assert.Equal(t, ha.ID(), s.Running[0].ID)
+	assert.Equal(t, pool.TaskRunning, s.Running[0].Status)
+	assert.Equal(t, "task a", s.Running[0].Description)
+	assert.Equal(t, ha.Worker(), s.Running[0].Worker)
+	assert.NotZero(t, s.Running[0].Worker)
+	assert.Equal(t, s.Taken.Sub(ha.Started()), s.Running[0].Elapsed)
+
+	assert.Equal(t, hb.ID(), s.Running[1].ID)
+	assert.Empty(t, s.Running[1].Description)
+	assert.NotEqual(t, s.Running[0].Worker, s.Running[1].Worker)
+
+	assert.Equal(t, hc.ID(), s.Queued[0].ID)
+	assert.Equal(t, pool.TaskQueued, s.Queued[0].Status)
+	assert.Equal(t, "task c", s.Queued[0].Description)
+	assert.Zero(t, s.Queued[0].Worker)
+	assert.True(t, s.Queued[0].Started.IsZero())
+
+	hc.Cancel()
+	close(a.release)
+	close(b.release)
+	workers.Stop()
+
+	s = workers.Snapshot()
+	assert.Empty(t, s.Running)
+	assert.Empty(t, s.Queued)
+}
+
+func TestNonBlocking_Snapshot(t *testing.T) {
+	workers := pool.NewNonBlocking[string](1)
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	h := workers.Start(context.Background(), replica{name: "slow", delay: time.Minute})
+	require.Eventually(t, func() bool { return h.Status() == pool.TaskRunning }, time.Second, time.Millisecond)
+
+	s := workers.Snapshot()
+	require.Len(t, s.Running, 1)
+	assert.Equal(t, h.ID(), s.Running[0].ID)
+	assert.Equal(t, 1, s.Running[0].Worker)
+
+	h.Cancel()
+	require.Error(t, h.Wait(context.Background()))
+}
@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"time"
+)
+
+// Stats keeps statistic values about a worker pool.
+type Stats struct {
+	// Workers is the number of workers in the pool.
+	Workers int
+	// Live is the number of started workers, it is less than Workers while the workers
+	// are spawned on demand (see WithIdleTimeout).
+	Live int
+	// Idle is the number of workers waiting for a task.
+	Idle int
+	// Running is the number of tasks being executed now.
+	Running int
+	// Queued is the number of tasks (or callers of a non-blocking pool) waiting for a worker
+	// in the pool queue.
+	Queued int
+	// Latency is the moving average of the task execution time.
+	Latency time.Duration
+	// Limit is the current number of tasks the pool may execute simultaneously.
+	Limit int
+	// Completed is the number of finished tasks.
+	Completed uint64
+	// Failed is the number of finished tasks which returned an error.
+	Failed uint64
+	// Rejected is the number of tasks skipped by the pool without running.
+	Rejected uint64
+}
+
+// Stats returns the current statistic values of the pool.
+func (c *core) Stats() Stats {
+	s := Stats{
+		Workers:   c.workers(),
+		Live:      int(c.live.Load()),
+		Idle:      int(c.idle.Load()),
+		Running:   int(c.running.Load()),
+		Limit:     c.limit(),
+		Latency:   time.Duration(c.latency.Load()),
+		Completed: c.completed.Load(),
+		Failed:    c.failed.Load(),
+		Rejected:  c.rejected.Load(),
+	}
+
+	if c.queue != nil {
+		s.Queued = c.queue.len()
+	}
+	if c.sched != nil {
+		s.Queued += c.sched.len()
+	}
+
+	return s
+}
+
+// limit returns the number of tasks the pool may execute simultaneously.
+func (c *core) limit() int {
+	n := c.workers()
+	if c.gate != nil {
+		if l := c.gate.limiter.Limit(); l < n {
+			n = l
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,69 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+type failing struct {
+	err error
+}
+
+func (f failing) Job(context.Context) pool.JobResponse[string] {
+	return pool.JobResponse[string]{Err: f.err}
+}
+
+func TestNonBlocking_Stats(t *testing.T) {
+	t.Run("Completed and failed tasks", func(t *testing.T) {
+		workers := pool.NewNonBlocking[string](3)
+		workers.Run(context.Background())
+		requests := workers.RequestChan()
+
+		for i := 0; i < 10; i++ {
+			var err error
+			if i%2 == 0 {
+				err = errors.New("failure")
+			}
+
+			req := <-requests
+			req.Request <- failing{err}
+			<-req.Response
+		}
+		workers.Stop()
+
+		stats := workers.Stats()
+		assert.Equal(t, 3, stats.Workers)
+		assert.Equal(t, 3, stats.Limit)
+		assert.Equal(t, 0, stats.Running)
+		assert.Equal(t, uint64(10), stats.Completed)
+		assert.Equal(t, uint64(5), stats.Failed)
+	})
+}
+
+func TestPool_Stats(t *testing.T) {
+	t.Run("Completed tasks", func(t *testing.T) {
+		var used budget
+		var wg sync.WaitGroup
+
+		workers := pool.New(2)
+		workers.Run(context.Background())
+
+		for i := 0; i < 7; i++ {
+			wg.Add(1)
+			workers.Execute(&heavy{1, &used, &wg})
+		}
+		wg.Wait()
+		workers.Stop()
+
+		stats := workers.Stats()
+		assert.Equal(t, uint64(7), stats.Completed)
+		assert.Equal(t, uint64(0), stats.Failed)
+		assert.Equal(t, uint64(0), stats.Rejected)
+	})
+}
@@ -0,0 +1,273 @@
+package pool
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Stealing is a worker pool for trees of CPU-bound tasks. Every worker keeps its own deque
+// of tasks: the subtasks spawned by a task (see Spawn) go to the deque of its worker,
+// which takes them in LIFO order, while the idle workers steal the oldest tasks of the others.
+// Tasks added with Execute are shared by all workers.
+type Stealing struct {
+	workersCnt int
+	inject     deque
+	deques     []*deque
+	pending    sync.WaitGroup
+	wg         sync.WaitGroup
+	sleeping   atomic.Int64
+	stopped    bool
+	mu         sync.Mutex
+	wake       *sync.Cond
+}
+
+// Subtask is a task spawned by Spawn. Join waits for it.
+type Subtask struct {
+	task Runner
+	pool *Stealing
+	done chan struct{}
+}
+
+// stealer is the worker of a Stealing pool running a task.
+type stealer struct {
+	pool *Stealing
+	id   int
+}
+
+type stealerKey struct{}
+
+// deque keeps the tasks of a worker. The owner takes them from the bottom, thieves from the top.
+type deque struct {
+	tasks []*Subtask
+	mu    sync.Mutex
+}
+
+// NewStealing creates a new work-stealing worker pool.
+func NewStealing(workersCnt int) *Stealing {
+	p := &Stealing{
+		workersCnt: workersCnt,
+		deques:     make([]*deque, workersCnt),
+	}
+	for i := range p.deques {
+		p.deques[i] = &deque{}
+	}
+	p.wake = sync.NewCond(&p.mu)
+
+	return p
+}
+
+// Run starts workers in the pool.
+func (p *Stealing) Run(ctx context.Context) {
+	for i := 0; i < p.workersCnt; i++ {
+		p.wg.Add(1)
+
+		go func(id int) {
+			defer p.wg.Done()
+
+			w := &stealer{pool: p, id: id}
+			ctx := context.WithValue(ctx, stealerKey{}, w)
+			for {
+				s := p.find(id)
+				if s == nil {
+					if !p.sleep() {
+						return
+					}
+					continue
+				}
+
+				s.run(ctx)
+			}
+		}(i)
+	}
+}
+
+// Stop stops workers in the pool.
+// All tasks added before Stop and their subtasks are executed.
+func (p *Stealing) Stop() {
+	p.pending.Wait()
+
+	p.mu.Lock()
+	p.stopped = true
+	p.wake.Broadcast()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// Execute adds a new task in the tasks queue shared by the workers of the pool.
+func (p *Stealing) Execute(task Runner) {
+	p.push(&p.inject, &Subtask{task: task, pool: p, done: make(chan struct{})})
+}
+
+// Spawn adds a subtask of the task running with ctx to the deque of its worker.
+// If ctx does not belong to a worker of a Stealing pool, the subtask is run at once.
+func Spawn(ctx context.Context, task Runner) *Subtask {
+	s := &Subtask{task: task, done: make(chan struct{})}
+
+	w, ok := ctx.Value(stealerKey{}).(*stealer)
+	if !ok {
+		task.Job(ctx)
+		close(s.done)
+		return s
+	}
+
+	s.pool = w.pool
+	w.pool.push(w.pool.deques[w.id], s)
+
+	return s
+}
+
+// Join waits until the subtask is finished. While waiting, the worker runs other tasks,
+// so a task may join its subtasks without occupying a worker.
+func (s *Subtask) Join(ctx context.Context) {
+	w, ok := ctx.Value(stealerKey{}).(*stealer)
+	if !ok || w.pool != s.pool {
+		<-s.done
+		return
+	}
+
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		next := s.pool.find(w.id)
+		if next == nil {
+			// The subtask is being run by another worker.
+			<-s.done
+			return
+		}
+
+		next.run(ctx)
+	}
+}
+
+// run executes the subtask with the context of the worker which took it.
+func (s *Subtask) run(ctx context.Context) {
+	defer s.pool.pending.Done()
+	defer close(s.done)
+
+	s.task.Job(ctx)
+}
+
+// push adds a task to a deque and wakes a sleeping worker.
+func (p *Stealing) push(d *deque, s *Subtask) {
+	p.pending.Add(1)
+	d.push(s)
+
+	if p.sleeping.Load() > 0 {
+		p.mu.Lock()
+		p.wake.Signal()
+		p.mu.Unlock()
+	}
+}
+
+// find takes a task from the deque of a worker, the shared queue, or steals it from another worker.
+func (p *Stealing) find(id int) *Subtask {
+	if s := p.deques[id].pop(); s != nil {
+		return s
+	}
+	if s := p.inject.steal(); s != nil {
+		return s
+	}
+
+	start := rand.Intn(len(p.deques))
+	for i := range p.deques {
+		victim := (start + i) % len(p.deques)
+		if victim == id {
+			continue
+		}
+
+		if s := p.deques[victim].steal(); s != nil {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// sleep waits until there is a task for the worker. It returns false when the pool is stopped.
+func (p *Stealing) sleep() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A task pushed after the worker is counted as sleeping wakes it up.
+	p.sleeping.Add(1)
+	defer p.sleeping.Add(-1)
+
+	for !p.stopped {
+		if p.hasTasks() {
+			return true
+		}
+
+		p.wake.Wait()
+	}
+
+	return false
+}
+
+// hasTasks reports whether any deque has a task.
+func (p *Stealing) hasTasks() bool {
+	if p.inject.len() > 0 {
+		return true
+	}
+
+	for _, d := range p.deques {
+		if d.len() > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *deque) push(s *Subtask) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.tasks = append(d.tasks, s)
+}
+
+// pop takes the newest task.
+func (d *deque) pop() *Subtask {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := len(d.tasks)
+	if n == 0 {
+		return nil
+	}
+
+	s := d.tasks[n-1]
+	d.tasks[n-1] = nil
+	d.tasks = d.tasks[:n-1]
+
+	return s
+}
+
+// steal takes the oldest task.
+func (d *deque) steal() *Subtask {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.tasks) == 0 {
+		return nil
+	}
+
+	s := d.tasks[0]
+	d.tasks[0] = nil
+	d.tasks = d.tasks[1:]
+
+	return s
+}
+
+func (d *deque) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.tasks)
+}
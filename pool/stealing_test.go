@@ -0,0 +1,190 @@
+package pool_test
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// fib computes a Fibonacci number spawning a subtask for every branch above the cutoff.
+type fib struct {
+	n      int
+	result *int
+}
+
+const fibCutoff = 10
+
+func (f fib) Job(ctx context.Context) {
+	if f.n < fibCutoff {
+		*f.result = fibSerial(f.n)
+		return
+	}
+
+	var a, b int
+	s := pool.Spawn(ctx, fib{f.n - 1, &a})
+	fib{f.n - 2, &b}.Job(ctx)
+	s.Join(ctx)
+
+	*f.result = a + b
+}
+
+func fibSerial(n int) int {
+	if n < 2 {
+		return n
+	}
+
+	return fibSerial(n-1) + fibSerial(n-2)
+}
+
+// leaf is a CPU-bound task without subtasks.
+type leaf struct {
+	n   int
+	sum *atomic.Int64
+	wg  *sync.WaitGroup
+}
+
+func (l leaf) Job(context.Context) {
+	l.sum.Add(int64(fibSerial(l.n)))
+	if l.wg != nil {
+		l.wg.Done()
+	}
+}
+
+func TestStealing(t *testing.T) {
+	t.Run("Spawn and Join", func(t *testing.T) {
+		workers := pool.NewStealing(4)
+		workers.Run(context.Background())
+
+		results := make([]int, 10)
+		for i := range results {
+			workers.Execute(fib{20 + i, &results[i]})
+		}
+		workers.Stop()
+
+		for i, r := range results {
+			assert.Equal(t, fibSerial(20+i), r)
+		}
+	})
+
+	t.Run("Join on a single worker", func(t *testing.T) {
+		workers := pool.NewStealing(1)
+		workers.Run(context.Background())
+
+		var result int
+		workers.Execute(fib{25, &result})
+		workers.Stop()
+
+		assert.Equal(t, fibSerial(25), result)
+	})
+
+	t.Run("Stop waits for subtasks which are not joined", func(t *testing.T) {
+		var sum atomic.Int64
+
+		workers := pool.NewStealing(2)
+		workers.Run(context.Background())
+		workers.Execute(spawner(func(ctx context.Context) {
+			for i := 0; i < 100; i++ {
+				pool.Spawn(ctx, leaf{n: 1, sum: &sum})
+			}
+		}))
+		workers.Stop()
+
+		assert.Equal(t, int64(100), sum.Load())
+	})
+
+	t.Run("Spawn outside of a pool runs the task at once", func(t *testing.T) {
+		var sum atomic.Int64
+
+		s := pool.Spawn(context.Background(), leaf{n: 10, sum: &sum})
+		assert.Equal(t, int64(55), sum.Load())
+		s.Join(context.Background())
+	})
+}
+
+type spawner func(ctx context.Context)
+
+func (s spawner) Job(ctx context.Context) {
+	s(ctx)
+}
+
+func BenchmarkPool_Tree(b *testing.B) {
+	var sum atomic.Int64
+	var wg sync.WaitGroup
+
+	workers := pool.New(runtime.GOMAXPROCS(0))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// The blocking pool has no Join, the tree is split by the caller.
+		var split func(n int)
+		split = func(n int) {
+			if n < fibCutoff {
+				wg.Add(1)
+				workers.Execute(leaf{n, &sum, &wg})
+				return
+			}
+
+			split(n - 1)
+			split(n - 2)
+		}
+		split(25)
+		wg.Wait()
+	}
+}
+
+func BenchmarkStealing_Tree(b *testing.B) {
+	workers := pool.NewStealing(runtime.GOMAXPROCS(0))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result int
+		done := make(chan struct{})
+		workers.Execute(spawner(func(ctx context.Context) {
+			fib{25, &result}.Job(ctx)
+			close(done)
+		}))
+		<-done
+	}
+}
+
+func BenchmarkPool_Execute(b *testing.B) {
+	var sum atomic.Int64
+	var wg sync.WaitGroup
+
+	workers := pool.New(runtime.GOMAXPROCS(0))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wg.Add(1)
+		workers.Execute(leaf{fibCutoff, &sum, &wg})
+	}
+	wg.Wait()
+}
+
+func BenchmarkStealing_Execute(b *testing.B) {
+	var sum atomic.Int64
+	var wg sync.WaitGroup
+
+	workers := pool.NewStealing(runtime.GOMAXPROCS(0))
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wg.Add(1)
+		workers.Execute(leaf{fibCutoff, &sum, &wg})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,190 @@
+package pool
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// WatchdogConfig keeps the settings of the watchdog of stuck tasks (see WithWatchdog).
+type WatchdogConfig struct {
+	// Threshold is the running time after which a task is stuck.
+	Threshold time.Duration
+	// Interval is the time between the checks of the running tasks. Default is a quarter of Threshold.
+	Interval time.Duration
+	// OnStuck is called once for every stuck task with its description and the stack
+	// of the goroutine of its worker.
+	OnStuck func(info TaskInfo, stack []byte)
+	// Cancel cancels the context of a stuck task.
+	Cancel bool
+	// ReplaceAfter is how long a stuck task may keep its worker. Then a new worker takes
+	// its place and the old one exits when the task returns. Zero means the worker is not replaced.
+	ReplaceAfter time.Duration
+}
+
+// States of a worker watched for stuck tasks.
+const (
+	workerWaiting int32 = iota
+	workerBusy
+	workerAbandoned
+)
+
+// watchdog checks the running tasks of a pool.
+type watchdog struct {
+	cfg WatchdogConfig
+	// replace starts a worker in place of an abandoned one.
+	replace func()
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// startWatchdog starts watching the running tasks if the pool has a watchdog (see WithWatchdog).
+func (c *core) startWatchdog(replace func()) {
+	if c.cfg.watchdog == nil {
+		return
+	}
+
+	c.watchdog = &watchdog{
+		cfg:     *c.cfg.watchdog,
+		replace: replace,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.watch()
+}
+
+// stopWatchdog stops watching the running tasks.
+func (c *core) stopWatchdog() {
+	if c.watchdog == nil {
+		return
+	}
+
+	close(c.watchdog.stop)
+	<-c.watchdog.done
+}
+
+// watch checks the running tasks until the watchdog is stopped.
+func (c *core) watch() {
+	defer close(c.watchdog.done)
+
+	ticker := c.cfg.clock.NewTicker(c.watchdog.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C():
+			for _, h := range c.Tasks() {
+				c.inspect(h, now)
+			}
+		case <-c.watchdog.stop:
+			return
+		}
+	}
+}
+
+// inspect reports a stuck task, cancels it and replaces its worker as configured.
+func (c *core) inspect(h *Handle, now time.Time) {
+	cfg := c.watchdog.cfg
+
+	h.mu.Lock()
+	if h.status != TaskRunning || now.Sub(h.started) < cfg.Threshold {
+		h.mu.Unlock()
+		return
+	}
+	first := h.stuck.IsZero()
+	if first {
+		h.stuck = now
+	}
+	stuck, w, cancel := h.stuck, h.w, h.cancel
+	h.mu.Unlock()
+
+	if first {
+		if cfg.OnStuck != nil {
+			var stack []byte
+			if w != nil {
+				stack = goroutineStack(w.goroutine)
+			}
+			cfg.OnStuck(h.info(now), stack)
+		}
+		if cfg.Cancel && cancel != nil {
+			cancel()
+		}
+	}
+
+	if cfg.ReplaceAfter > 0 && now.Sub(stuck) >= cfg.ReplaceAfter && h.abandon() {
+		c.watchdog.replace()
+	}
+}
+
+// mark records the goroutine of a worker for the stacks of the stuck tasks.
+// It is called by the goroutine of the worker.
+func (c *core) mark(w *worker) {
+	if c.cfg.watchdog != nil {
+		w.goroutine = goroutineID()
+	}
+}
+
+// busy marks a worker running a task.
+func (w *worker) busy() {
+	w.watch.Store(workerBusy)
+}
+
+// release marks a worker which has finished a task waiting again.
+// It returns false if the worker has been abandoned by the watchdog and has to exit.
+func (w *worker) release() bool {
+	return w.watch.CompareAndSwap(workerBusy, workerWaiting)
+}
+
+// abandon gives up a worker running a stuck task. It returns false if the task has just finished.
+func (w *worker) abandon() bool {
+	return w.watch.CompareAndSwap(workerBusy, workerAbandoned)
+}
+
+// abandon gives up the worker of a stuck task. It returns false if the task has finished meanwhile.
+// The status is checked under the lock, so the worker cannot have taken another task.
+func (h *Handle) abandon() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.status == TaskRunning && h.w != nil && h.w.abandon()
+}
+
+// abandoned reports whether a worker has been abandoned by the watchdog.
+func (w *worker) abandoned() bool {
+	return w.watch.Load() == workerAbandoned
+}
+
+// goroutineID returns the ID of the current goroutine, as it is printed in its stack.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(b[:i]), 10, 64)
+		return id
+	}
+
+	return 0
+}
+
+// goroutineStack returns the stack of a goroutine, or nil if it is not found.
+func goroutineStack(id uint64) []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, prefix) {
+			return stack
+		}
+	}
+
+	return nil
+}
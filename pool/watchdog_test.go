@@ -0,0 +1,108 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// stuckReport keeps the reports of the watchdog.
+type stuckReport struct {
+	infos  []pool.TaskInfo
+	stacks [][]byte
+	mu     sync.Mutex
+}
+
+func (r *stuckReport) onStuck(info pool.TaskInfo, stack []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.infos = append(r.infos, info)
+	r.stacks = append(r.stacks, stack)
+}
+
+func (r *stuckReport) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.infos)
+}
+
+func TestPool_WithWatchdog(t *testing.T) {
+	t.Run("Stuck task is reported and cancelled", func(t *testing.T) {
+		var report stuckReport
+		workers := pool.New(1, pool.WithWatchdog(pool.WatchdogConfig{
+			Threshold: 20 * time.Millisecond,
+			OnStuck:   report.onStuck,
+			Cancel:    true,
+		}))
+		workers.Run(context.Background())
+		defer workers.Stop()
+
+		started := make(cancellable)
+		h := workers.Execute(pool.Fallible(started))
+		assert.ErrorIs(t, h.Wait(context.Background()), context.Canceled)
+		assert.Equal(t, pool.TaskFailed, h.Status())
+
+		require.Equal(t, 1, report.len())
+		assert.Equal(t, h.ID(), report.infos[0].ID)
+		assert.GreaterOrEqual(t, report.infos[0].Elapsed, 20*time.Millisecond)
+		assert.Contains(t, string(report.stacks[0]), "cancellable.Job")
+	})
+
+	t.Run("Stuck worker is replaced", func(t *testing.T) {
+		var report stuckReport
+		workers := pool.New(1, pool.WithWatchdog(pool.WatchdogConfig{
+			Threshold:    10 * time.Millisecond,
+			OnStuck:      report.onStuck,
+			Cancel:       true,
+			ReplaceAfter: 10 * time.Millisecond,
+		}))
+		workers.Run(context.Background())
+
+		// The task ignores the cancelled context.
+		stuck := described{newBlocked(), "stuck"}
+		h := workers.Execute(stuck)
+		<-stuck.started
+
+		next := workers.Execute(signal(make(chan struct{}, 1)))
+		require.NoError(t, next.Wait(context.Background()))
+		assert.Equal(t, pool.TaskRunning, h.Status())
+		assert.Equal(t, 1, workers.Stats().Live)
+
+		require.Equal(t, 1, report.len())
+		assert.Equal(t, "task stuck", report.infos[0].Description)
+
+		// Stop does not wait for the abandoned worker.
+		workers.Stop()
+		close(stuck.release)
+		require.NoError(t, h.Wait(context.Background()))
+	})
+}
+
+func TestNonBlocking_WithWatchdog(t *testing.T) {
+	workers := pool.NewNonBlocking[int](1, pool.WithWatchdog(pool.WatchdogConfig{
+		Threshold:    10 * time.Millisecond,
+		ReplaceAfter: 10 * time.Millisecond,
+	}))
+	workers.Run(context.Background())
+
+	release := make(hold)
+	h := workers.Start(context.Background(), release)
+	require.Eventually(t, func() bool { return h.Status() == pool.TaskRunning }, time.Second, time.Millisecond)
+
+	resp := workers.Submit(context.Background(), workerOfResponse{})
+	require.NoError(t, resp.Err)
+	assert.Equal(t, 2, resp.Value)
+	assert.Equal(t, pool.TaskRunning, h.Status())
+
+	workers.Stop()
+	close(release)
+	require.NoError(t, h.Wait(context.Background()))
+}
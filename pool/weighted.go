@@ -0,0 +1,136 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Weigher is an optional interface for a task which costs more (or less) than an ordinary one.
+// The weight of a task is taken from the pool capacity while the task is running.
+type Weigher interface {
+	Weight() int64
+}
+
+// weightOf returns the weight of a task limited by the size of the budget.
+func weightOf(task any, size int64) int64 {
+	w := int64(1)
+	if t, ok := taskAs[Weigher](task); ok {
+		w = t.Weight()
+	}
+
+	switch {
+	case w < 0:
+		return 0
+	case w > size:
+		return size
+	}
+
+	return w
+}
+
+// semaphore is a weighted semaphore which serves waiters in FIFO order.
+type semaphore struct {
+	size    int64
+	cur     int64
+	waiters list.List
+	mu      sync.Mutex
+}
+
+// waiter keeps a weight requested from the semaphore and a channel closed when it is granted.
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newSemaphore(size int64) *semaphore {
+	return &semaphore{size: size}
+}
+
+// acquire takes n from the semaphore, blocking until it is available or ctx is done.
+func (s *semaphore) acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	w := waiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			// Acquired after ctx was done, give it back.
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// A removed head may have blocked smaller waiters behind it.
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+
+		return ctx.Err()
+	}
+}
+
+// tryAcquire takes n from the semaphore without blocking and reports whether it succeeded.
+func (s *semaphore) tryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+
+	return false
+}
+
+// release returns n to the semaphore.
+func (s *semaphore) release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	s.notifyWaiters()
+}
+
+// used returns the amount currently taken from the semaphore.
+func (s *semaphore) used() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cur
+}
+
+func (s *semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			// Do not let smaller waiters overtake the head, otherwise a heavy task starves.
+			return
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,143 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// budget keeps the sum of weights of running tasks and its observed maximum.
+type budget struct {
+	cur  int64
+	max  int64
+	done int
+	mu   sync.Mutex
+}
+
+type heavy struct {
+	weight int64
+	used   *budget
+	wg     *sync.WaitGroup
+}
+
+func (h *heavy) Weight() int64 {
+	return h.weight
+}
+
+func (h *heavy) Job(context.Context) {
+	h.start()
+	time.Sleep(time.Millisecond)
+	h.finish()
+	h.wg.Done()
+}
+
+func (h *heavy) start() {
+	h.used.mu.Lock()
+	h.used.cur += h.weight
+	if h.used.cur > h.used.max {
+		h.used.max = h.used.cur
+	}
+	h.used.mu.Unlock()
+}
+
+func (h *heavy) finish() {
+	h.used.mu.Lock()
+	h.used.cur -= h.weight
+	h.used.done++
+	h.used.mu.Unlock()
+}
+
+type heavyResponse struct {
+	heavy
+}
+
+func (h *heavyResponse) Job(context.Context) pool.JobResponse[int64] {
+	h.start()
+	time.Sleep(time.Millisecond)
+	h.finish()
+
+	return pool.JobResponse[int64]{Value: h.weight}
+}
+
+func TestPool_WithCapacity(t *testing.T) {
+	t.Run("Running weights stay within capacity", func(t *testing.T) {
+		const capacity = 10
+
+		var used budget
+		var wg sync.WaitGroup
+
+		workers := pool.New(8, pool.WithCapacity(capacity))
+		workers.Run(context.Background())
+
+		for i := 0; i < 60; i++ {
+			weight := int64(3)
+			if i%10 == 0 {
+				weight = capacity
+			}
+
+			wg.Add(1)
+			workers.Execute(&heavy{weight, &used, &wg})
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, 60, used.done)
+		assert.LessOrEqual(t, used.max, int64(capacity))
+		assert.Equal(t, int64(0), used.cur)
+	})
+
+	t.Run("Task heavier than capacity is clamped", func(t *testing.T) {
+		var used budget
+		var wg sync.WaitGroup
+
+		workers := pool.New(2, pool.WithCapacity(5))
+		workers.Run(context.Background())
+
+		wg.Add(2)
+		workers.Execute(&heavy{100, &used, &wg})
+		workers.Execute(&heavy{1, &used, &wg})
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, 2, used.done)
+		assert.Equal(t, int64(100), used.max)
+	})
+}
+
+func TestNonBlocking_WithCapacity(t *testing.T) {
+	t.Run("Running weights stay within capacity", func(t *testing.T) {
+		const (
+			capacity = 4
+			total    = 30
+		)
+
+		var used budget
+		var wg sync.WaitGroup
+
+		workers := pool.NewNonBlocking[int64](6, pool.WithCapacity(capacity))
+		workers.Run(context.Background())
+		requests := workers.RequestChan()
+
+		for i := 0; i < total; i++ {
+			wg.Add(1)
+			req := <-requests
+			req.Request <- &heavyResponse{heavy{int64(i%3 + 1), &used, nil}}
+
+			go func() {
+				defer wg.Done()
+				r := <-req.Response
+				assert.NoError(t, r.Err)
+			}()
+		}
+		wg.Wait()
+		workers.Stop()
+
+		assert.Equal(t, total, used.done)
+		assert.LessOrEqual(t, used.max, int64(capacity))
+	})
+}
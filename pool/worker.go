@@ -0,0 +1,126 @@
+package pool
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// worker keeps the identity, the local state and the lifecycle values of a worker.
+type worker struct {
+	id      int
+	state   any
+	started time.Time
+	tasks   int
+	timer   Timer
+	// expiry fires when the worker reaches its maximum lifetime, it is nil without the limit.
+	expiry <-chan time.Time
+	// goroutine is the ID of the goroutine of the worker, it is set only with a watchdog.
+	goroutine uint64
+	// watch is the state of the worker for the watchdog of stuck tasks.
+	watch atomic.Int32
+	// nudged is set when the worker has stopped waiting for a task to check the pool settings.
+	nudged bool
+	// wakeup is taken before the worker checks the pool settings, so it does not miss a nudge
+	// coming after the check (see core.nudged).
+	wakeup <-chan struct{}
+}
+
+type workerKey struct{}
+
+// WorkerID returns the ID of the worker running a task with ctx.
+// IDs start from 1 and are unique within a pool, a worker keeps its ID while it runs.
+func WorkerID(ctx context.Context) (int, bool) {
+	w, ok := ctx.Value(workerKey{}).(*worker)
+	if !ok {
+		return 0, false
+	}
+
+	return w.id, true
+}
+
+// WorkerState returns the local state of the worker running a task with ctx (see WithWorkerState).
+// It reports false if the pool has no worker state of type S.
+func WorkerState[S any](ctx context.Context) (S, bool) {
+	w, ok := ctx.Value(workerKey{}).(*worker)
+	if !ok {
+		var zero S
+		return zero, false
+	}
+
+	s, ok := w.state.(S)
+	return s, ok
+}
+
+// newWorker gives a starting worker its ID and local state and returns the context of its tasks.
+func (c *core) newWorker(ctx context.Context) (context.Context, *worker) {
+	w := &worker{
+		id:      int(c.workerSeq.Add(1)),
+		started: c.cfg.clock.Now(),
+	}
+	if c.cfg.workerState != nil {
+		w.state = c.cfg.workerState()
+	}
+	if c.cfg.maxLifetime > 0 {
+		w.timer = c.cfg.clock.NewTimer(c.cfg.maxLifetime)
+		w.expiry = w.timer.C()
+	}
+
+	ctx = context.WithValue(ctx, workerKey{}, w)
+	if c.cfg.onWorkerStart != nil {
+		c.cfg.onWorkerStart(ctx)
+	}
+
+	return ctx, w
+}
+
+// retired reports whether a worker has to be replaced after the task it has finished.
+func (c *core) retired(w *worker) bool {
+	return c.cfg.maxTasks > 0 && w.tasks >= c.cfg.maxTasks ||
+		c.cfg.maxLifetime > 0 && c.cfg.clock.Now().Sub(w.started) >= c.cfg.maxLifetime
+}
+
+// stopWorker tears down a stopped or retired worker and releases its local state.
+func (c *core) stopWorker(ctx context.Context, w *worker) {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	if c.cfg.onWorkerStop != nil {
+		c.cfg.onWorkerStop(ctx)
+	}
+	if s, ok := w.state.(io.Closer); ok {
+		_ = s.Close()
+	}
+}
+
+// lazy reports whether workers are spawned on demand (see WithIdleTimeout).
+func (c *core) lazy() bool {
+	return c.cfg.idleTimeout > 0
+}
+
+// reserve counts a new worker in if the demand exceeds the idle workers
+// and the pool has room for one more.
+func (c *core) reserve(demand int64) bool {
+	for {
+		n := c.live.Load()
+		if n >= c.size.Load() || demand <= c.idle.Load() {
+			return false
+		}
+
+		if c.live.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// idleTimer returns a channel which fires when a waiting worker has been idle for too long
+// and a function to stop the timer. The channel is nil if idle workers are kept.
+func (c *core) idleTimer() (<-chan time.Time, func()) {
+	if !c.lazy() {
+		return nil, func() {}
+	}
+
+	t := c.cfg.clock.NewTimer(c.cfg.idleTimeout)
+	return t.C(), func() { t.Stop() }
+}
@@ -0,0 +1,362 @@
+package pool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/illyasch/worker-pool/pool"
+)
+
+// scratch is a worker state which records that it has been closed.
+type scratch struct {
+	buf    []byte
+	closed bool
+}
+
+func (s *scratch) Close() error {
+	s.closed = true
+	return nil
+}
+
+// identify records the worker which runs it and the state of the worker.
+type identify struct {
+	ids     map[int]*scratch
+	changed *int
+	mu      *sync.Mutex
+	wg      *sync.WaitGroup
+}
+
+func (i identify) Job(ctx context.Context) {
+	defer i.wg.Done()
+
+	id, ok := pool.WorkerID(ctx)
+	if !ok {
+		return
+	}
+	s, _ := pool.WorkerState[*scratch](ctx)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if prev, ok := i.ids[id]; ok && prev != s {
+		*i.changed++
+	}
+	i.ids[id] = s
+}
+
+type identifyResponse struct {
+	identify
+}
+
+func (i identifyResponse) Job(ctx context.Context) pool.JobResponse[int] {
+	i.wg.Add(1)
+	i.identify.Job(ctx)
+
+	id, _ := pool.WorkerID(ctx)
+	return pool.JobResponse[int]{Value: id}
+}
+
+func TestPool_WithWorkerState(t *testing.T) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var changed int
+	ids := make(map[int]*scratch)
+
+	workers := pool.New(3, pool.WithWorkerState(func() *scratch {
+		return &scratch{buf: make([]byte, 1024)}
+	}))
+	workers.Run(context.Background())
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		workers.Execute(identify{ids: ids, changed: &changed, mu: &mu, wg: &wg})
+	}
+	wg.Wait()
+	workers.Stop()
+
+	require.NotEmpty(t, ids)
+	assert.Zero(t, changed)
+	for id, s := range ids {
+		assert.True(t, id >= 1 && id <= 3, "worker ID %d", id)
+		require.NotNil(t, s)
+		assert.Len(t, s.buf, 1024)
+		assert.True(t, s.closed)
+	}
+}
+
+func TestNonBlocking_WithWorkerState(t *testing.T) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var changed int
+	ids := make(map[int]*scratch)
+
+	workers := pool.NewNonBlocking[int](2, pool.WithWorkerState(func() *scratch {
+		return &scratch{}
+	}))
+	workers.Run(context.Background())
+
+	for i := 0; i < 20; i++ {
+		resp := workers.Submit(context.Background(), identifyResponse{identify{ids: ids, changed: &changed, mu: &mu, wg: &wg}})
+		require.NoError(t, resp.Err)
+		assert.Contains(t, []int{1, 2}, resp.Value)
+	}
+	workers.Stop()
+
+	assert.Zero(t, changed)
+	for id, s := range ids {
+		assert.Contains(t, []int{1, 2}, id)
+		assert.True(t, s.closed)
+	}
+}
+
+func TestWorkerID(t *testing.T) {
+	t.Run("Outside of a worker", func(t *testing.T) {
+		_, ok := pool.WorkerID(context.Background())
+		assert.False(t, ok)
+
+		_, ok = pool.WorkerState[*scratch](context.Background())
+		assert.False(t, ok)
+	})
+
+	t.Run("Without worker state", func(t *testing.T) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var changed int
+		ids := make(map[int]*scratch)
+
+		workers := pool.New(1)
+		workers.Run(context.Background())
+		wg.Add(1)
+		workers.Execute(identify{ids: ids, changed: &changed, mu: &mu, wg: &wg})
+		wg.Wait()
+		workers.Stop()
+
+		require.Contains(t, ids, 1)
+		assert.Nil(t, ids[1])
+	})
+}
+
+// lifecycle records the starts and stops of workers.
+type lifecycle struct {
+	started []int
+	// startedBeforeStop keeps the number of started workers at the moment a worker stopped.
+	startedBeforeStop map[int]int
+	mu                sync.Mutex
+}
+
+func (l *lifecycle) hooks() pool.Option {
+	l.startedBeforeStop = make(map[int]int)
+
+	return pool.WithWorkerHooks(
+		func(ctx context.Context) {
+			id, _ := pool.WorkerID(ctx)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.started = append(l.started, id)
+		},
+		func(ctx context.Context) {
+			id, _ := pool.WorkerID(ctx)
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.startedBeforeStop[id] = len(l.started)
+		},
+	)
+}
+
+func (l *lifecycle) starts() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.started)
+}
+
+// workerOf records the ID of the worker which runs it.
+type workerOf struct {
+	ids *[]int
+}
+
+func (w workerOf) Job(ctx context.Context) {
+	id, _ := pool.WorkerID(ctx)
+	*w.ids = append(*w.ids, id)
+}
+
+func (w workerOf) Response(ctx context.Context) pool.JobResponse[int] {
+	id, _ := pool.WorkerID(ctx)
+	return pool.JobResponse[int]{Value: id}
+}
+
+type workerOfResponse struct {
+	workerOf
+}
+
+func (w workerOfResponse) Job(ctx context.Context) pool.JobResponse[int] {
+	return w.Response(ctx)
+}
+
+func TestPool_WithMaxTasks(t *testing.T) {
+	var l lifecycle
+	var ids []int
+
+	workers := pool.New(1, pool.WithMaxTasks(2), l.hooks())
+	workers.Run(context.Background())
+	for i := 0; i < 6; i++ {
+		workers.Execute(workerOf{&ids})
+	}
+	workers.Stop()
+
+	assert.Equal(t, []int{1, 1, 2, 2, 3, 3}, ids)
+	assert.Equal(t, []int{1, 2, 3, 4}, l.started)
+	// Every retired worker is torn down after its successor has started.
+	for id := 1; id <= 3; id++ {
+		assert.GreaterOrEqual(t, l.startedBeforeStop[id], id+1, "worker %d", id)
+	}
+	assert.Equal(t, uint64(6), workers.Stats().Completed)
+}
+
+func TestPool_WithMaxLifetime(t *testing.T) {
+	var l lifecycle
+
+	workers := pool.New(2, pool.WithMaxLifetime(10*time.Millisecond), l.hooks())
+	workers.Run(context.Background())
+
+	// Idle workers are replaced too.
+	require.Eventually(t, func() bool { return l.starts() >= 6 }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, workers.Stats().Workers)
+
+	var ids []int
+	workers.Execute(workerOf{&ids})
+	workers.Stop()
+
+	require.Len(t, ids, 1)
+	assert.Greater(t, ids[0], 2)
+	assert.Len(t, l.startedBeforeStop, l.starts())
+}
+
+func TestNonBlocking_WithMaxTasks(t *testing.T) {
+	var l lifecycle
+
+	workers := pool.NewNonBlocking[int](1, pool.WithMaxTasks(1), l.hooks())
+	workers.Run(context.Background())
+
+	for i := 1; i <= 3; i++ {
+		resp := workers.Submit(context.Background(), workerOfResponse{})
+		require.NoError(t, resp.Err)
+		assert.Equal(t, i, resp.Value)
+	}
+	workers.Stop()
+
+	// The last worker is replaced unless the pool has been stopped meanwhile.
+	require.GreaterOrEqual(t, len(l.started), 3)
+	assert.Equal(t, []int{1, 2, 3}, l.started[:3])
+	assert.Len(t, l.startedBeforeStop, len(l.started))
+}
+
+func TestNonBlocking_WithMaxLifetime(t *testing.T) {
+	var l lifecycle
+
+	workers := pool.NewNonBlocking[int](1, pool.WithMaxLifetime(10*time.Millisecond), l.hooks())
+	workers.Run(context.Background())
+	defer workers.Stop()
+
+	require.Eventually(t, func() bool { return l.starts() >= 3 }, time.Second, time.Millisecond)
+
+	resp := workers.Submit(context.Background(), workerOfResponse{})
+	require.NoError(t, resp.Err)
+	assert.Greater(t, resp.Value, 2)
+}
+
+// hold blocks the worker of a non-blocking pool until it is released.
+type hold chan struct{}
+
+func (h hold) Job(context.Context) pool.JobResponse[int] {
+	<-h
+	return pool.JobResponse[int]{}
+}
+
+func TestPool_WithIdleTimeout(t *testing.T) {
+	for name, opts := range map[string][]pool.Option{
+		"Direct":    nil,
+		"Scheduled": {pool.WithTenants(pool.TenantConfig{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, pool.WithIdleTimeout(20*time.Millisecond))
+			workers := pool.New(3, opts...)
+			workers.Run(context.Background())
+			assert.Equal(t, 0, workers.Stats().Live)
+
+			gates := []blocked{newBlocked(), newBlocked(), newBlocked()}
+			for _, g := range gates {
+				workers.Execute(g)
+				<-g.started
+			}
+			assert.Equal(t, 3, workers.Stats().Live)
+
+			// The fourth task waits for a busy worker, the pool does not exceed its size.
+			done := make(signal)
+			go workers.Execute(done)
+			time.Sleep(10 * time.Millisecond)
+			assert.Equal(t, 3, workers.Stats().Live)
+
+			for _, g := range gates {
+				close(g.release)
+			}
+			<-done
+
+			// Idle workers exit, a new task spawns a worker again.
+			require.Eventually(t, func() bool { return workers.Stats().Live == 0 }, time.Second, time.Millisecond)
+
+			var ids []int
+			workers.Execute(workerOf{&ids})
+			workers.Stop()
+
+			require.Len(t, ids, 1)
+			assert.Greater(t, ids[0], 3)
+			assert.Equal(t, uint64(5), workers.Stats().Completed)
+			assert.Equal(t, 0, workers.Stats().Live)
+		})
+	}
+}
+
+func TestNonBlocking_WithIdleTimeout(t *testing.T) {
+	for name, opts := range map[string][]pool.Option{
+		"Direct": nil,
+		"Queued": {pool.WithQueue(pool.QueueConfig{})},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, pool.WithIdleTimeout(20*time.Millisecond))
+			workers := pool.NewNonBlocking[int](2, opts...)
+			workers.Run(context.Background())
+			defer workers.Stop()
+			assert.Equal(t, 0, workers.Stats().Live)
+
+			release := make(hold)
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp := workers.Submit(context.Background(), release)
+					assert.NoError(t, resp.Err)
+				}()
+			}
+
+			require.Eventually(t, func() bool { return workers.Stats().Running == 2 }, time.Second, time.Millisecond)
+			assert.Equal(t, 2, workers.Stats().Live)
+
+			close(release)
+			wg.Wait()
+
+			require.Eventually(t, func() bool { return workers.Stats().Live == 0 }, time.Second, time.Millisecond)
+
+			resp := workers.Submit(context.Background(), workerOfResponse{})
+			require.NoError(t, resp.Err)
+			assert.Greater(t, resp.Value, 2)
+		})
+	}
+}